@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Values of --access-list.
+const (
+	accessListNone = "none"
+	accessListAuto = "auto"
+)
+
+// createAccessList asks the node for the access list of the deposit call via
+// eth_createAccessList and reports the gas it is estimated to save.
+func createAccessList(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg) (types.AccessList, error) {
+	arg := map[string]interface{}{
+		"from":  msg.From,
+		"to":    msg.To,
+		"value": (*hexutil.Big)(msg.Value),
+		"input": hexutil.Bytes(msg.Data),
+	}
+	var result struct {
+		AccessList types.AccessList `json:"accessList"`
+		Error      string           `json:"error"`
+		GasUsed    hexutil.Uint64   `json:"gasUsed"`
+	}
+	if err := client.Client().CallContext(ctx, &result, "eth_createAccessList", arg, "pending"); err != nil {
+		return nil, fmt.Errorf("eth_createAccessList failed: %w", err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("eth_createAccessList execution failed: %s", result.Error)
+	}
+
+	without, err := client.EstimateGas(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas: %w", err)
+	}
+	msg.AccessList = result.AccessList
+	with, err := client.EstimateGas(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas with access list: %w", err)
+	}
+
+	saved := new(big.Int).Sub(new(big.Int).SetUint64(without), new(big.Int).SetUint64(with))
+	fmt.Printf("Access list has %d entries, estimated gas %d without and %d with it (saves %s)\n",
+		len(result.AccessList), without, with, saved)
+	return result.AccessList, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const defaultBatchMethod = "batchDeposit"
+
+// batchCall is a wrapper contract method taking the pubkeys, withdrawal
+// credentials, signatures and deposit data roots of several deposits as
+// arrays, paid with the sum of their amounts.
+type batchCall struct {
+	method abi.Method
+}
+
+// newBatchCall looks method up in contractABI and checks its parameters.
+// The canonical deposit contract has no such method.
+func newBatchCall(contractABI abi.ABI, name string) (batchCall, error) {
+	method, ok := contractABI.Methods[name]
+	if !ok {
+		return batchCall{}, fmt.Errorf("method %q is not in the ABI", name)
+	}
+	want := []string{"bytes[]", "bytes[]", "bytes[]", "bytes32[]"}
+	if len(method.Inputs) != len(want) {
+		return batchCall{}, fmt.Errorf("method %s must take (bytes[],bytes[],bytes[],bytes32[])", method.Sig)
+	}
+	for i, t := range want {
+		if got := method.Inputs[i].Type.String(); got != t {
+			return batchCall{}, fmt.Errorf("method %s must take (bytes[],bytes[],bytes[],bytes32[]), parameter %d is %s", method.Sig, i, got)
+		}
+	}
+	return batchCall{method: method}, nil
+}
+
+// SubmitBatch sends deposits in a single call of the batch method. An error
+// means nothing was sent: a *sendError if the batch could not be sent, else
+// one of the entries is invalid.
+func (s *Submitter) SubmitBatch(call batchCall, deposits []DepositData) (*types.Transaction, error) {
+	var pubkeys, withdrawalCredentials, signatures [][]byte
+	var roots [][32]byte
+	amountWei := new(big.Int)
+	for _, data := range deposits {
+		if err := data.checkFieldLengths(); err != nil {
+			return nil, fmt.Errorf("deposit %d: %w", data.index, err)
+		}
+		pubkeys = append(pubkeys, data.PubKey)
+		withdrawalCredentials = append(withdrawalCredentials, data.WithdrawalCredentials)
+		signatures = append(signatures, data.Signature)
+		roots = append(roots, data.root())
+		value, err := s.depositValue(data)
+		if err != nil {
+			return nil, err
+		}
+		amountWei.Add(amountWei, value)
+	}
+
+	args := []interface{}{pubkeys, withdrawalCredentials, signatures, roots}
+	packed, err := call.method.Inputs.Pack(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack arguments: %w", packError(call.method, args, err))
+	}
+	packedData := append(append([]byte{}, call.method.ID...), packed...)
+
+	fmt.Printf("Batch of %d deposits\n", len(deposits))
+	return s.send(deposits, packedData, amountWei, s.cfg.GasLimit*uint64(len(deposits)))
+}
+
+// printBatchGas reports the gas of a mined batch and the transaction
+// overhead it saved compared to one transaction per deposit.
+func printBatchGas(receipt *types.Receipt, size int) {
+	saved := uint64(size-1) * params.TxGas
+	fmt.Printf("Batch of %d deposits used %d gas (%d per deposit), saving about %d gas of transaction overhead\n",
+		size, receipt.GasUsed, receipt.GasUsed/uint64(size), saved)
+}
+
+// transactionCount is the number of transactions sending deposits in
+// batches of batchSize.
+func transactionCount(deposits, batchSize int) int {
+	return (deposits + batchSize - 1) / batchSize
+}
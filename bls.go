@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+)
+
+// blsDST is the domain separation tag of the proof-of-possession BLS scheme
+// the beacon chain signs with.
+var blsDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+// verifyBLSSignature checks that signature is the BLS signature of
+// signingRoot by pubkey, both compressed points; decoding them checks that
+// they are in the right subgroups.
+func verifyBLSSignature(pubkey, signature []byte, signingRoot [32]byte) error {
+	var pk bls12381.G1Affine
+	if _, err := pk.SetBytes(pubkey); err != nil {
+		return fmt.Errorf("invalid pubkey: %w", err)
+	}
+	if pk.IsInfinity() {
+		return errors.New("invalid pubkey: point at infinity")
+	}
+	var sig bls12381.G2Affine
+	if _, err := sig.SetBytes(signature); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	message, err := bls12381.HashToG2(signingRoot[:], blsDST)
+	if err != nil {
+		return err
+	}
+
+	// e(pk, H(m)) == e(g1, sig)
+	_, _, g1, _ := bls12381.Generators()
+	var negG1 bls12381.G1Affine
+	negG1.Neg(&g1)
+	ok, err := bls12381.PairingCheck([]bls12381.G1Affine{pk, negG1}, []bls12381.G2Affine{message, sig})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("signature does not match the pubkey and signing root")
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// broadcastResult is the outcome of one pre-signed transaction.
+type broadcastResult struct {
+	line    int
+	tx      *types.Transaction
+	receipt *types.Receipt
+	err     error
+}
+
+// readRawTransactions reads one hex encoded signed transaction per line,
+// skipping blank lines and # comments, or the txBundle written by --output
+// bundle, whose transactions are numbered by their position.
+func readRawTransactions(path string) ([]broadcastResult, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(file); len(trimmed) > 0 && trimmed[0] == '{' {
+		var bundle txBundle
+		if err := json.Unmarshal(trimmed, &bundle); err != nil {
+			return nil, fmt.Errorf("invalid transaction bundle: %w", err)
+		}
+		txs := make([]broadcastResult, len(bundle.Transactions))
+		for i, text := range bundle.Transactions {
+			tx, err := decodeRawTransaction(text)
+			if err != nil {
+				return nil, fmt.Errorf("transaction %d: %w", i+1, err)
+			}
+			txs[i] = broadcastResult{line: i + 1, tx: tx}
+		}
+		return txs, nil
+	}
+
+	var txs []broadcastResult
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		tx, err := decodeRawTransaction(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		txs = append(txs, broadcastResult{line: line, tx: tx})
+	}
+	return txs, scanner.Err()
+}
+
+// decodeRawTransaction decodes a 0x prefixed EIP-2718 encoded transaction.
+func decodeRawTransaction(text string) (*types.Transaction, error) {
+	raw, err := hexutil.Decode(text)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("invalid transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// runBroadcast sends the pre-signed transactions of path, then waits for
+// their receipts. It needs no private key, the transactions are sent as
+// they are. Transactions the node already knows or that are already mined
+// count as sent.
+func runBroadcast(cfg Config, path string) error {
+	results, err := readRawTransactions(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("%s has no transactions", path)
+	}
+	ctx := context.Background()
+
+	client, err := dialClient(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the Ethereum client: %w", err)
+	}
+	chainID, err := resolveChainID(ctx, client, cfg.ChainID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Chain ID: %d\n", chainID)
+	for _, r := range results {
+		if r.tx.Protected() && r.tx.ChainId().Cmp(chainID) != 0 {
+			return fmt.Errorf("transaction %s (line %d) is signed for chain %s, the node is on chain %s; nothing was sent", r.tx.Hash().Hex(), r.line, r.tx.ChainId(), chainID)
+		}
+	}
+
+	for i := range results {
+		r := &results[i]
+		if r.err = checkDeadline(ctx, client, cfg.ValidUntilBlock); r.err != nil {
+			fmt.Printf("Not sending %s (line %d): %v\n", r.tx.Hash().Hex(), r.line, r.err)
+			continue
+		}
+		err := client.SendTransaction(ctx, r.tx)
+		switch {
+		case err == nil:
+			fmt.Printf("Sent %s with nonce %d\n", r.tx.Hash().Hex(), r.tx.Nonce())
+		case isAlreadyKnown(err):
+			fmt.Printf("Node already knows %s\n", r.tx.Hash().Hex())
+		case isNonceTooLow(err):
+			// Either this transaction was mined before or another one took its nonce
+			if _, _, lookupErr := client.TransactionByHash(ctx, r.tx.Hash()); lookupErr == nil {
+				fmt.Printf("%s is already mined\n", r.tx.Hash().Hex())
+			} else {
+				r.err = fmt.Errorf("nonce %d was used by another transaction", r.tx.Nonce())
+			}
+		default:
+			r.err = err
+		}
+		if r.err != nil {
+			fmt.Printf("Failed to send %s (line %d): %v\n", r.tx.Hash().Hex(), r.line, r.err)
+		}
+	}
+
+	failed := 0
+	for i := range results {
+		r := &results[i]
+		if r.err == nil {
+			r.receipt, r.err = waitMined(ctx, client, r.tx, cfg.Poll())
+		}
+		if r.err != nil || r.receipt.Status != types.ReceiptStatusSuccessful {
+			failed++
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tTX\tNONCE\tBLOCK\tRESULT")
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			fmt.Fprintf(tw, "%d\t%s\t%d\t\t%v\n", r.line, r.tx.Hash().Hex(), r.tx.Nonce(), r.err)
+		case r.receipt.Status != types.ReceiptStatusSuccessful:
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%d\treverted (status %d)\n", r.line, r.tx.Hash().Hex(), r.tx.Nonce(), r.receipt.BlockNumber, r.receipt.Status)
+		default:
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%d\tsucceeded\n", r.line, r.tx.Hash().Hex(), r.tx.Nonce(), r.receipt.BlockNumber)
+		}
+	}
+	tw.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d transactions failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	outputText   = "text"
+	outputBundle = "bundle"
+)
+
+// txBundle is the --output bundle document: the EIP-2718 encoding of every
+// signed transaction and, at the same positions, their hashes and the
+// entries of the deposit file they carry.
+type txBundle struct {
+	Transactions []string `json:"transactions"`
+	Hashes       []string `json:"hashes"`
+	Indices      [][]int  `json:"indices"`
+}
+
+// writeBundle writes txs as a txBundle to w, indices holding the entries of
+// each transaction.
+func writeBundle(w io.Writer, txs []*types.Transaction, indices [][]int) error {
+	bundle := txBundle{Transactions: []string{}, Hashes: []string{}, Indices: indices}
+	if bundle.Indices == nil {
+		bundle.Indices = [][]int{}
+	}
+	for _, tx := range txs {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		bundle.Transactions = append(bundle.Transactions, hexutil.Encode(raw))
+		bundle.Hashes = append(bundle.Hashes, tx.Hash().Hex())
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bundle)
+}
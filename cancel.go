@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// priceBump is the minimum fee increase in percent of a replacement
+// transaction accepted by the default geth transaction pool.
+const priceBump = 10
+
+// bumpedFee is the lowest fee replacing a transaction paying fee.
+func bumpedFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+priceBump))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// replacementFee returns the fee for a replacement of a transaction paying
+// old: configured if set, which must then be high enough, or else the
+// higher of suggested and the minimum bump.
+func replacementFee(name string, old, configured, suggested *big.Int) (*big.Int, error) {
+	minimum := bumpedFee(old)
+	if configured != nil {
+		if configured.Cmp(minimum) < 0 {
+			return nil, fmt.Errorf("%s of %s gwei does not replace the stuck transaction, it needs at least %s gwei (+%d%%)",
+				name, formatWeiAsGwei(configured), formatWeiAsGwei(minimum), priceBump)
+		}
+		return configured, nil
+	}
+	if suggested.Cmp(minimum) > 0 {
+		return suggested, nil
+	}
+	return minimum, nil
+}
+
+// runCancel replaces the pending transaction txHash of the account with a
+// zero-value transaction to itself, so that its nonce is freed and the
+// deposit it carries never happens.
+func runCancel(cfg Config, txHash string) error {
+	if !isHexHash(txHash) {
+		return fmt.Errorf("invalid transaction hash %q", txHash)
+	}
+	ctx := context.Background()
+
+	signer, err := newTxSigner(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid signing key: %w", err)
+	}
+	defer signer.Close()
+	from := signer.Address()
+
+	client, err := dialClient(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the Ethereum client: %w", err)
+	}
+	chainID, err := resolveChainID(ctx, client, cfg.ChainID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Chain ID: %d\n", chainID)
+
+	stuck, isPending, err := client.TransactionByHash(ctx, common.HexToHash(txHash))
+	if err != nil {
+		return fmt.Errorf("failed to get transaction %s: %w", txHash, err)
+	}
+	if !isPending {
+		return fmt.Errorf("transaction %s is already mined, there is nothing to cancel", txHash)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), stuck)
+	if err != nil {
+		return fmt.Errorf("failed to recover the sender of %s: %w", txHash, err)
+	}
+	if sender != from {
+		return fmt.Errorf("transaction %s was sent by %s, not by the signing key %s", txHash, sender.Hex(), from.Hex())
+	}
+
+	suggestedFee, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get gas fee cap: %w", err)
+	}
+	feeCap, err := replacementFee("--gas-fee-cap", stuck.GasFeeCap(), cfg.GasFeeCap, suggestedFee)
+	if err != nil {
+		return err
+	}
+
+	var cancel *types.Transaction
+	if stuck.Type() == types.LegacyTxType || stuck.Type() == types.AccessListTxType {
+		cancel = types.NewTx(&types.LegacyTx{Nonce: stuck.Nonce(), GasPrice: feeCap, Gas: params.TxGas, To: &from})
+	} else {
+		suggestedTip, err := client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get gas tip cap: %w", err)
+		}
+		tipCap, err := replacementFee("--gas-tip-cap", stuck.GasTipCap(), cfg.GasTipCap, suggestedTip)
+		if err != nil {
+			return err
+		}
+		if tipCap.Cmp(feeCap) > 0 {
+			feeCap = tipCap
+		}
+		cancel = types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: stuck.Nonce(), GasTipCap: tipCap, GasFeeCap: feeCap, Gas: params.TxGas, To: &from})
+	}
+
+	fmt.Printf("Stuck transaction %s: nonce %d, value %s ETH, max fee %s gwei\n", txHash, stuck.Nonce(), formatWeiAsETH(stuck.Value()), formatWeiAsGwei(stuck.GasFeeCap()))
+	fmt.Printf("Replacement: 0 ETH to %s, max fee %s gwei, tip %s gwei\n", from.Hex(), formatWeiAsGwei(cancel.GasFeeCap()), formatWeiAsGwei(cancel.GasTipCap()))
+	if !cfg.Yes {
+		fmt.Printf("Cancelling means the deposit of this transaction will NOT happen. Cancel it? (y/n): ")
+		answer, answered := readLineTimeout(cfg.ConfirmTimeout)
+		if !answered || answer != "y" {
+			return errors.New("cancel aborted, nothing was sent")
+		}
+	}
+
+	signed, err := signTx(signer, cancel, chainID)
+	if err != nil {
+		return fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	if err := client.SendTransaction(ctx, signed); err != nil {
+		return fmt.Errorf("failed to send transaction: %w", err)
+	}
+	fmt.Printf("Cancel transaction sent: %s\n", signed.Hash().Hex())
+
+	receipt, err := waitMined(ctx, client, signed, cfg.Poll())
+	if err != nil {
+		return fmt.Errorf("failed to wait for %s: %w", signed.Hash().Hex(), err)
+	}
+	fmt.Printf("Nonce %d is free: cancel transaction mined in block %d with status %d\n", stuck.Nonce(), receipt.BlockNumber, receipt.Status)
+	return nil
+}
+
+func isHexHash(s string) bool {
+	b, err := hexutil.Decode(s)
+	return err == nil && len(b) == common.HashLength
+}
@@ -0,0 +1,606 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// testPrivateKey is the first account of the "test test ... junk" mnemonic,
+// 0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266.
+const testPrivateKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+var (
+	binaryOnce sync.Once
+	binaryDir  string
+	binaryPath string
+	binaryErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if binaryDir != "" {
+		os.RemoveAll(binaryDir)
+	}
+	os.Exit(code)
+}
+
+// cliBinary builds go-deposit once for all CLI tests.
+func cliBinary(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds the binary")
+	}
+	binaryOnce.Do(func() {
+		binaryDir, binaryErr = os.MkdirTemp("", "go-deposit-cli")
+		if binaryErr != nil {
+			return
+		}
+		binaryPath = filepath.Join(binaryDir, "go-deposit")
+		out, err := exec.Command("go", "build", "-o", binaryPath, ".").CombinedOutput()
+		if err != nil {
+			binaryErr = errors.New(string(out))
+		}
+	})
+	if binaryErr != nil {
+		t.Fatalf("failed to build go-deposit: %v", binaryErr)
+	}
+	return binaryPath
+}
+
+// cliRun is one run of the binary.
+type cliRun struct {
+	dir    string
+	env    []string
+	stdin  string
+	output string
+	code   int
+}
+
+// newCLIRun prepares a run in a fresh directory with abi.json, RPC_URL
+// pointing at node and the test account as PRIVATE_KEY.
+func newCLIRun(t *testing.T, node *fakeNode) *cliRun {
+	t.Helper()
+	dir := t.TempDir()
+	abiFile, err := os.ReadFile("abi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "abi.json"), abiFile, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		"XDG_CONFIG_HOME=" + filepath.Join(dir, "config"),
+		"PRIVATE_KEY=" + testPrivateKey,
+	}
+	if node != nil {
+		env = append(env, "RPC_URL="+node.URL)
+	}
+	return &cliRun{dir: dir, env: env}
+}
+
+// writeDeposits writes entries to deposit_data.json in the run directory.
+func (r *cliRun) writeDeposits(t *testing.T, entries []map[string]any) string {
+	t.Helper()
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(r.dir, "deposit_data.json")
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// run runs the binary with args and records its combined output and exit code.
+func (r *cliRun) run(t *testing.T, args ...string) {
+	t.Helper()
+	cmd := exec.Command(cliBinary(t), args...)
+	cmd.Dir = r.dir
+	cmd.Env = r.env
+	cmd.Stdin = strings.NewReader(r.stdin)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	r.output = out.String()
+	r.code = 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		r.code = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (r *cliRun) expect(t *testing.T, code int, want ...string) {
+	t.Helper()
+	if r.code != code {
+		t.Errorf("exit code %d, want %d\n%s", r.code, code, r.output)
+	}
+	for _, s := range want {
+		if !strings.Contains(r.output, s) {
+			t.Errorf("output does not contain %q\n%s", s, r.output)
+		}
+	}
+}
+
+// testEntry is a deposit file entry of amountGwei whose pubkey repeats seed,
+// withdrawing to the test account, with a valid deposit_data_root.
+func testEntry(t testing.TB, seed byte, amountGwei uint64) map[string]any {
+	t.Helper()
+	pubkey := bytes.Repeat([]byte{seed}, pubkeyLength)
+	credentials := append([]byte{eth1AddressWithdrawalPrefix}, make([]byte, 11)...)
+	credentials = append(credentials, common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266").Bytes()...)
+	signature := bytes.Repeat([]byte{seed}, signatureLength)
+	root, err := computeDepositDataRoot(pubkey, credentials, amountGwei, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]any{
+		"pubkey":                 hex.EncodeToString(pubkey),
+		"withdrawal_credentials": hex.EncodeToString(credentials),
+		"amount":                 amountGwei,
+		"signature":              hex.EncodeToString(signature),
+		"deposit_data_root":      hex.EncodeToString(root[:]),
+	}
+}
+
+// testDepositData is testEntry as read from a deposit file.
+func testDepositData(t testing.TB, seed byte, amountGwei uint64) DepositData {
+	t.Helper()
+	raw, err := json.Marshal(testEntry(t, seed, amountGwei))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deposits, err := decodeDeposits(raw, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deposits[0]
+}
+
+const holeskyChainID = 17000
+
+func TestCLIDeposits(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	r := newCLIRun(t, node)
+	path := r.writeDeposits(t, []map[string]any{testEntry(t, 0x11, 32_000_000_000), testEntry(t, 0x22, 1_000_000_000)})
+
+	r.run(t, "--yes", path)
+	r.expect(t, 0, "Deposit data has 2 entries", "Chain ID: 17000 (holesky)", "Deposit 0 SUCCEEDED", "Deposit 1 SUCCEEDED")
+
+	sent := node.Sent()
+	if len(sent) != 2 {
+		t.Fatalf("%d transactions sent, want 2", len(sent))
+	}
+	holesky, _ := networkByChainID(big.NewInt(holeskyChainID))
+	want := []*big.Int{new(big.Int).Mul(big.NewInt(32), big.NewInt(1e18)), big.NewInt(1e18)}
+	for i, tx := range sent {
+		if *tx.To() != holesky.DepositContract {
+			t.Errorf("transaction %d sent to %s, want the holesky deposit contract %s", i, tx.To().Hex(), holesky.DepositContract.Hex())
+		}
+		if tx.Value().Cmp(want[i]) != 0 {
+			t.Errorf("transaction %d has value %s wei, want %s", i, tx.Value(), want[i])
+		}
+		if tx.Type() != types.DynamicFeeTxType {
+			t.Errorf("transaction %d has type %d, want a dynamic fee transaction", i, tx.Type())
+		}
+	}
+
+	// The ledger of the first run keeps a second one from depositing again
+	r.run(t, "--yes", path)
+	r.expect(t, 0, "All deposits were already made")
+	if len(node.Sent()) != 2 {
+		t.Errorf("the second run sent %d transactions", len(node.Sent())-2)
+	}
+}
+
+func TestCLIFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(node *fakeNode, r *cliRun)
+		entries func(t *testing.T) []map[string]any
+		args    []string
+		noFile  bool
+		code    int
+		want    []string
+		sent    int
+	}{
+		{
+			name:   "no deposit file",
+			args:   []string{"--yes"},
+			noFile: true,
+			code:   2,
+			want:   []string{"Usage: go-deposit"},
+		},
+		{
+			name:   "missing file",
+			args:   []string{"--yes", "missing.json"},
+			noFile: true,
+			code:   1,
+			want:   []string{"Failed to read deposit_data.json file"},
+		},
+		{
+			name:  "no RPC_URL",
+			setup: func(_ *fakeNode, r *cliRun) { r.env = r.env[:len(r.env)-1] },
+			args:  []string{"--yes", "--env-file", ""},
+			code:  1,
+			want:  []string{"Invalid configuration: RPC_URL is not set"},
+		},
+		{
+			name: "invalid entry",
+			entries: func(t *testing.T) []map[string]any {
+				entry := testEntry(t, 0x11, 32_000_000_000)
+				entry["deposit_data_root"] = strings.Repeat("00", 32)
+				return []map[string]any{entry}
+			},
+			code: 1,
+			want: []string{"Invalid entry 0", "nothing was sent"},
+		},
+		{
+			name: "duplicate entry",
+			entries: func(t *testing.T) []map[string]any {
+				return []map[string]any{testEntry(t, 0x11, 32_000_000_000), testEntry(t, 0x11, 32_000_000_000)}
+			},
+			code: 1,
+			want: []string{"Warning: entry 1 duplicates entry 0", "1 duplicate entries are invalid and stop the run", "repeats the pubkey and amount of entry 0", "nothing was sent"},
+		},
+		{
+			name: "forced past invalid entries",
+			entries: func(t *testing.T) []map[string]any {
+				entries := []map[string]any{testEntry(t, 0x11, 32_000_000_000), testEntry(t, 0x22, 32_000_000_000), testEntry(t, 0x33, 32_000_000_000), testEntry(t, 0x11, 32_000_000_000)}
+				entries[1]["deposit_data_root"] = strings.Repeat("00", 32)
+				return entries
+			},
+			args: []string{"--yes", "--force"},
+			code: 1,
+			want: []string{"WARNING: --force drops 2 invalid entries: 1, 3", "Deposit 0 SUCCEEDED", "Deposit 2 SUCCEEDED", "2 deposits were not sent because their deposit data is invalid"},
+			sent: 2,
+		},
+		{
+			name: "wrong network",
+			entries: func(t *testing.T) []map[string]any {
+				entry := testEntry(t, 0x11, 32_000_000_000)
+				entry["fork_version"] = "00000000"
+				return []map[string]any{entry}
+			},
+			code: 1,
+			want: []string{"WRONG NETWORK", "nothing was sent"},
+		},
+		{
+			name: "wrong network with --force",
+			entries: func(t *testing.T) []map[string]any {
+				entry := testEntry(t, 0x11, 32_000_000_000)
+				entry["fork_version"] = "00000000"
+				return []map[string]any{entry}
+			},
+			args: []string{"--yes", "--force"},
+			code: 1,
+			want: []string{"WRONG NETWORK", "use --allow-network-mismatch to deposit anyway"},
+		},
+		{
+			name: "wrong network allowed",
+			entries: func(t *testing.T) []map[string]any {
+				entry := testEntry(t, 0x11, 32_000_000_000)
+				entry["fork_version"] = "00000000"
+				return []map[string]any{entry}
+			},
+			args: []string{"--yes", "--allow-network-mismatch"},
+			want: []string{"WARNING: --allow-network-mismatch overrides the chain of 1 entries", "Deposit 0 SUCCEEDED"},
+			sent: 1,
+		},
+		{
+			name: "genesis root of another network",
+			entries: func(t *testing.T) []map[string]any {
+				entry := testEntry(t, 0x11, 32_000_000_000)
+				entry["genesis_validators_root"] = strings.Repeat("ab", 32)
+				return []map[string]any{entry}
+			},
+			args: []string{"--yes", "--force"},
+			code: 1,
+			want: []string{"generated for another network than holesky, nothing was sent; use --allow-network-mismatch"},
+		},
+		{
+			name:  "declined",
+			setup: func(_ *fakeNode, r *cliRun) { r.stdin = "n\n" },
+			args:  []string{},
+			code:  1,
+			want:  []string{"Confirm transaction?", "Stopping at deposit 0, nothing was sent for it: transaction cancelled", "Summary:"},
+		},
+		{
+			name:  "rejected by the node",
+			setup: func(node *fakeNode, _ *cliRun) { node.sendError = "insufficient funds for gas * price + value" },
+			code:  1,
+			want:  []string{"failed to send transaction: insufficient funds", "Summary:"},
+		},
+		{
+			name:  "reverted",
+			setup: func(node *fakeNode, _ *cliRun) { node.revert = true },
+			code:  1,
+			want:  []string{"Deposit 0 REVERTED", "Summary:"},
+			sent:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := newFakeNode(t, holeskyChainID)
+			r := newCLIRun(t, node)
+			if tt.setup != nil {
+				tt.setup(node, r)
+			}
+			args := tt.args
+			if args == nil {
+				args = []string{"--yes"}
+			}
+			if !tt.noFile {
+				entries := []map[string]any{testEntry(t, 0x11, 32_000_000_000)}
+				if tt.entries != nil {
+					entries = tt.entries(t)
+				}
+				args = append(args, r.writeDeposits(t, entries))
+			}
+			r.run(t, args...)
+			r.expect(t, tt.code, tt.want...)
+			if got := len(node.Sent()); got != tt.sent {
+				t.Errorf("%d transactions sent, want %d", got, tt.sent)
+			}
+		})
+	}
+}
+
+func TestCLIReview(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	// Deposited by another operator, the run below has no receipt of it
+	first := newCLIRun(t, node)
+	first.run(t, "--yes", first.writeDeposits(t, []map[string]any{testEntry(t, 0x11, 32_000_000_000)}))
+	first.expect(t, 0, "Deposit 0 SUCCEEDED")
+
+	// Entry 1 tops up that validator, entry 0 is new
+	r := newCLIRun(t, node)
+	path := r.writeDeposits(t, []map[string]any{testEntry(t, 0x22, 32_000_000_000), testEntry(t, 0x11, 32_000_000_000), testEntry(t, 0x33, 32_000_000_000)})
+	r.stdin = "t 2\na 0 1000000000\na 1 1000000000\ny\ny\ny\n"
+	r.run(t, "--interactive", path)
+	r.expect(t, 0, "[x] 1  ", "[ ] 2  ",
+		"entry 0 has no deposit on-chain yet and its signature covers the amount, sign the deposit again for the new amount",
+		"Warning: the signature does not cover the new amount, entry 1 is only valid as a top-up", "Deposit 0 SUCCEEDED", "Deposit 1 SUCCEEDED")
+
+	sent := node.Sent()
+	if len(sent) != 3 {
+		t.Fatalf("%d transactions sent, want 3", len(sent))
+	}
+	want := []*big.Int{new(big.Int).Mul(big.NewInt(32), big.NewInt(1e18)), big.NewInt(1e18)}
+	for i, tx := range sent[1:] {
+		if tx.Value().Cmp(want[i]) != 0 {
+			t.Errorf("deposit %d sent %s wei, want %s", i, tx.Value(), want[i])
+		}
+	}
+}
+
+// TestCLIReviewMessageRoot changes the amount of a top-up from a file with a
+// deposit_message_root, which the changed amount no longer matches.
+func TestCLIReviewMessageRoot(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	file, err := os.ReadFile("testdata/deposit_data-holesky.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []map[string]any
+	if err := json.Unmarshal(file, &entries); err != nil {
+		t.Fatal(err)
+	}
+	first := newCLIRun(t, node)
+	first.run(t, "--yes", first.writeDeposits(t, entries))
+	first.expect(t, 0, "Verified the deposit_message_root of 1 entries and the signature of 1", "Deposit 0 SUCCEEDED")
+
+	r := newCLIRun(t, node)
+	r.stdin = "a 0 1000000000\ny\ny\ny\n"
+	r.run(t, "--interactive", r.writeDeposits(t, entries))
+	r.expect(t, 0, "The deposit_message_root and signature of 1 top-ups with a changed amount were not checked", "Deposit 0 SUCCEEDED")
+	if sent := node.Sent(); len(sent) != 2 || sent[1].Value().Cmp(big.NewInt(1e18)) != 0 {
+		t.Fatalf("%d transactions sent, want a second one of 1 ETH", len(sent))
+	}
+}
+
+func TestCLIPlan(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	r := newCLIRun(t, node)
+	path := r.writeDeposits(t, []map[string]any{testEntry(t, 0x11, 32_000_000_000)})
+
+	r.run(t, "plan", path)
+	r.expect(t, 0, "Deposit contract code hash")
+	if node.Calls("eth_sendRawTransaction") != 0 {
+		t.Errorf("plan sent %d transactions", node.Calls("eth_sendRawTransaction"))
+	}
+}
+
+// TestCLIPlanWrapper plans through a wrapper: the existing deposits are
+// those of the deposit contract behind it, not of the wrapper's address.
+func TestCLIPlanWrapper(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	node.wrapper = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	r := newCLIRun(t, node)
+	r.addWrapperMethod(t)
+	path := r.writeDeposits(t, []map[string]any{testEntry(t, 0x11, 32_000_000_000)})
+
+	r.run(t, "--yes", "--contract", node.wrapper.Hex(), "--deposit-method", "depositFor", path)
+	r.expect(t, 0)
+
+	path = r.writeDeposits(t, []map[string]any{testEntry(t, 0x11, 1_000_000_000), testEntry(t, 0x22, 32_000_000_000)})
+	r.run(t, "plan", "--ignore-ledger", "--contract", node.wrapper.Hex(), "--deposit-method", "depositFor", path)
+	r.expect(t, 0)
+	for _, want := range []string{shortPubkey(strings.Repeat("11", pubkeyLength)) + "  top-up", shortPubkey(strings.Repeat("22", pubkeyLength)) + "  new"} {
+		if !strings.Contains(r.output, want) {
+			t.Errorf("plan does not contain %q\n%s", want, r.output)
+		}
+	}
+}
+
+func TestCLINetworkFailure(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	node.sendError = "connection reset by peer"
+	r := newCLIRun(t, node)
+	path := r.writeDeposits(t, []map[string]any{testEntry(t, 0x11, 32_000_000_000)})
+
+	r.run(t, "--yes", "--summary-file", "summary.json", path)
+	r.expect(t, 1, "Summary:", "failed to send transaction")
+
+	outcomes, err := readReport(filepath.Join(r.dir, "summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 || outcomes[0].Status != outcomeFailedNetwork {
+		t.Fatalf("summary %+v, want one entry %q", outcomes, outcomeFailedNetwork)
+	}
+	if !isRetryable(outcomes[0].Status) {
+		t.Errorf("%q is not retryable", outcomes[0].Status)
+	}
+}
+
+func TestCLIReceiptTimeout(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	node.neverMine = true
+	r := newCLIRun(t, node)
+	entry := testEntry(t, 0x11, 32_000_000_000)
+	path := r.writeDeposits(t, []map[string]any{entry})
+
+	r.run(t, "--yes", "--receipt-timeout", "1s", "--poll-interval", "100ms", "--summary-file", "summary.json", path)
+	r.expect(t, 1, "no receipt within --receipt-timeout 1s", "Deposit 0 ("+shortPubkey(entry["pubkey"].(string))+") is in flight", "Summary:")
+	if len(node.Sent()) != 1 {
+		t.Fatalf("%d transactions sent, want 1", len(node.Sent()))
+	}
+
+	outcomes, err := readReport(filepath.Join(r.dir, "summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 || outcomes[0].Status != outcomeInFlight || outcomes[0].TxHash != node.Sent()[0].Hash().Hex() {
+		t.Fatalf("summary %+v, want the sent transaction %s in flight", outcomes, node.Sent()[0].Hash().Hex())
+	}
+	if isRetryable(outcomes[0].Status) {
+		t.Errorf("%q is retryable", outcomes[0].Status)
+	}
+}
+
+// addWrapperMethod adds the wrapper's depositFor, which takes the parameters
+// of deposit, to the abi.json of the run.
+func (r *cliRun) addWrapperMethod(t *testing.T) {
+	t.Helper()
+	var entries []map[string]any
+	abiFile, _ := os.ReadFile(filepath.Join(r.dir, "abi.json"))
+	if err := json.Unmarshal(abiFile, &entries); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry["name"] == "deposit" {
+			depositFor := make(map[string]any)
+			for k, v := range entry {
+				depositFor[k] = v
+			}
+			depositFor["name"] = "depositFor"
+			entries = append(entries, depositFor)
+			break
+		}
+	}
+	abiFile, _ = json.Marshal(entries)
+	if err := os.WriteFile(filepath.Join(r.dir, "abi.json"), abiFile, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCLIWrapper(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	node.wrapper = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	r := newCLIRun(t, node)
+	r.addWrapperMethod(t)
+	path := r.writeDeposits(t, []map[string]any{testEntry(t, 0x11, 32_000_000_000)})
+
+	holesky, _ := networkByChainID(big.NewInt(holeskyChainID))
+	r.run(t, "--yes", "--contract", node.wrapper.Hex(), "--deposit-method", "depositFor", path)
+	r.expect(t, 0, "Deposit contract "+holesky.DepositContract.Hex()+" has deposit root", "5 deposits so far", "Wrapper contract "+node.wrapper.Hex()+" has code hash")
+	if sent := node.Sent(); len(sent) != 1 || *sent[0].To() != node.wrapper {
+		t.Fatalf("sent %d transactions, want one to the wrapper", len(sent))
+	}
+
+	// The DepositEvent comes from the deposit contract behind the wrapper
+	path = r.writeDeposits(t, []map[string]any{testEntry(t, 0x22, 32_000_000_000)})
+	r.run(t, "--yes", "--verify-after-submit", "--contract", node.wrapper.Hex(), "--deposit-method", "depositFor", path)
+	r.expect(t, 0, "verified with deposit index 6")
+
+	r.run(t, "doctor", "--contract", node.wrapper.Hex(), "--deposit-method", "depositFor", path)
+	r.expect(t, 0, "[PASS] Wrapper contract: "+node.wrapper.Hex(), "[PASS] Deposit contract: "+holesky.DepositContract.Hex())
+}
+
+// TestCLISignOnly signs without sending and hands the bundle to broadcast.
+func TestCLISignOnly(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	r := newCLIRun(t, node)
+	path := r.writeDeposits(t, []map[string]any{testEntry(t, 0x11, 32_000_000_000), testEntry(t, 0x22, 32_000_000_000)})
+
+	r.run(t, "--yes", "--sign-only", path)
+	r.expect(t, 0, "Signed 2 transactions, none was sent")
+	if sent := node.Sent(); len(sent) != 0 {
+		t.Fatalf("%d transactions sent", len(sent))
+	}
+	start, end := strings.Index(r.output, "{\n"), strings.Index(r.output, "\n}\n")
+	if start < 0 || end < start {
+		t.Fatalf("no bundle in the output\n%s", r.output)
+	}
+	var bundle txBundle
+	if err := json.Unmarshal([]byte(r.output[start:end+2]), &bundle); err != nil || len(bundle.Transactions) != 2 {
+		t.Fatalf("bundle %+v (%v), want 2 transactions", bundle, err)
+	}
+	bundlePath := filepath.Join(r.dir, "bundle.json")
+	if err := os.WriteFile(bundlePath, []byte(r.output[start:end+2]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r.run(t, "broadcast", bundlePath)
+	r.expect(t, 0, "Sent "+bundle.Hashes[0], "Sent "+bundle.Hashes[1])
+	if sent := node.Sent(); len(sent) != 2 {
+		t.Errorf("%d transactions sent, want the 2 of the bundle", len(sent))
+	}
+}
+
+func TestCLIEnvFile(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	r := newCLIRun(t, node)
+	path := r.writeDeposits(t, []map[string]any{testEntry(t, 0x11, 32_000_000_000)})
+
+	// Without a .env the environment is used
+	r.run(t, "plan", path)
+	r.expect(t, 0, "Warning: .env not found, using the environment")
+
+	// --env-file "" never reads the .env, not even one in the directory
+	var env []string
+	for _, v := range r.env {
+		if !strings.HasPrefix(v, "PRIVATE_KEY=") {
+			env = append(env, v)
+		}
+	}
+	r.env = env
+	if err := os.WriteFile(filepath.Join(r.dir, ".env"), []byte("PRIVATE_KEY="+testPrivateKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r.run(t, "plan", path)
+	r.expect(t, 0, "Deposit contract code hash")
+	r.run(t, "--env-file", "", "plan", path)
+	r.expect(t, 1, "PRIVATE_KEY is not set")
+	if strings.Contains(r.output, ".env") {
+		t.Errorf("output mentions .env\n%s", r.output)
+	}
+}
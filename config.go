@@ -0,0 +1,664 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	nonceSourcePending = "pending"
+	nonceSourceLatest  = "latest"
+)
+
+const (
+	// defaultContractAddress is used on chains without a known deposit contract.
+	defaultContractAddress = "0x4242424242424242424242424242424242424242"
+	defaultGasLimit        = 300000
+)
+
+// Config holds every tunable of a deposit run. DefaultConfig provides the
+// starting values, the CLI overrides them from flags and the environment.
+type Config struct {
+	// EnvFile is the dotenv file read before the environment, .env by
+	// default; empty reads none. Only the CLI reads it, LoadEnv and the rest
+	// of the package see the environment alone.
+	EnvFile    string
+	RPCURL     string
+	PrivateKey string
+	// CrossVerify holds additional RPC endpoints every transaction is sent
+	// to and every receipt is compared with.
+	CrossVerify []string
+	// DepositProfileFile is a JSON network profile merged into the network
+	// table, for networks the tool does not know.
+	DepositProfileFile string
+	// MnemonicFile holds a BIP-39 mnemonic the signing key is derived from
+	// along HDPath, instead of PRIVATE_KEY. The mnemonic is never a flag.
+	MnemonicFile string
+	HDPath       string
+	// Signer is where the signing key is kept: signerKey for PRIVATE_KEY or
+	// MnemonicFile, signerPKCS11 for the HSM behind PKCS11Module. The pin
+	// of its slot is read from PKCS11_PIN and never logged.
+	Signer         string
+	PKCS11Module   string
+	PKCS11Slot     uint
+	PKCS11KeyLabel string
+	PKCS11Pin      string
+	// ExpectedFrom, when set, must be the address of the signing key.
+	ExpectedFrom string
+	// ExpectedWithdrawalAddress, when set, is the execution address every
+	// entry's withdrawal credentials must carry.
+	ExpectedWithdrawalAddress string
+	// ChainID, when set, must be the chain ID reported by the node.
+	ChainID uint64
+
+	// ContractAddress comes from --contract or DEPOSIT_CONTRACT; when empty the
+	// deposit contract of the network is used, see ResolveContract.
+	ContractAddress string
+	// ConfirmContractAddress confirms a custom contract address without the
+	// prompt, it has to repeat the address.
+	ConfirmContractAddress string
+	// ContractCodeHash, when set, must be the keccak256 of the contract code.
+	ContractCodeHash string
+	// DepositMethod is the contract method called for every deposit, with
+	// DepositArgs as the values of its parameters after the standard four.
+	DepositMethod string
+	DepositArgs   []string
+	// AbiFromExplorer fetches the verified ABI of the contract from
+	// ExplorerAPIURL, authenticated with ExplorerAPIKey, instead of relying
+	// on abi.json alone.
+	AbiFromExplorer bool
+	ExplorerAPIURL  string
+	ExplorerAPIKey  string
+	// BatchSize groups this many deposits into one call of BatchMethod, for
+	// wrapper contracts that support it.
+	BatchSize   int
+	BatchMethod string
+	GasLimit    uint64
+	// ParallelGasEstimation estimates the gas limit of every deposit with this
+	// many concurrent requests before the first is sent, 0 uses GasLimit.
+	ParallelGasEstimation int
+	// GasStrategy picks the EIP-1559 fees that are not configured, see gasStrategies.
+	GasStrategy string
+	// GasTipCap and GasFeeCap are in wei and replace the gas strategy when set.
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+	// WarnTip is the suggested tip in wei above which a warning is shown,
+	// the network's threshold when nil and never when zero.
+	WarnTip *big.Int
+
+	// NonceSource is nonceSourcePending or nonceSourceLatest.
+	NonceSource string
+	// PrintNonces prints the first and the expected last nonce of the batch.
+	PrintNonces bool
+
+	// TxType is txTypeAuto, txTypeDynamic or txTypeLegacy.
+	TxType string
+	// AccessList is accessListNone or accessListAuto.
+	AccessList string
+
+	// RPS limits the number of RPC requests per second, 0 means unlimited.
+	RPS float64
+	// MaxConnections caps the HTTP connections to the node, open and idle,
+	// 0 means the limits of Go's default transport.
+	MaxConnections int
+
+	// Interactive lets the operator review the entries before submitting,
+	// Yes skips every confirmation prompt.
+	Interactive bool
+	Yes         bool
+	// ConfirmTimeout cancels a confirmation prompt left unanswered this long,
+	// 0 waits forever.
+	ConfirmTimeout time.Duration
+	// ConfirmDetails shows the full transaction JSON with every confirmation.
+	ConfirmDetails bool
+	// PrintDepositSummaryBefore prints the table of all entries before the
+	// first confirmation also with --yes; interactive runs always do.
+	PrintDepositSummaryBefore bool
+	// ConfirmEachWithDiff shows only the fields of an entry that differ from
+	// the previously confirmed one.
+	ConfirmEachWithDiff bool
+	// InteractiveGas asks to approve the fees before each transaction is
+	// signed, with the option to fetch them again. --yes skips it.
+	InteractiveGas bool
+
+	// StartAtBlock and StartAtTime delay the first deposit until the chain
+	// reaches that block number and block timestamp.
+	StartAtBlock uint64
+	StartAtTime  time.Time
+
+	// Simulate runs every deposit through eth_call before asking for confirmation.
+	Simulate bool
+	// SimulateAll simulates every deposit before the first is sent and
+	// aborts if any fails.
+	SimulateAll bool
+	// AbortOnInvalid stops the run at the first entry with invalid deposit
+	// data instead of skipping it and exiting with an error at the end.
+	AbortOnInvalid bool
+	// IgnoreRevert keeps going after a reverted deposit instead of stopping
+	// the run and exiting with an error.
+	IgnoreRevert bool
+	// VerifyAfterSubmit checks the DepositEvent and deposit count of every mined deposit.
+	VerifyAfterSubmit bool
+
+	// NoWait sends every deposit before waiting for any receipt, the receipts
+	// are then fetched by up to ReceiptWorkers goroutines.
+	NoWait         bool
+	ReceiptWorkers int
+	// PollInterval is the first interval of polling for receipts and
+	// confirmations, each following one PollBackoff times longer up to
+	// PollMaxInterval.
+	PollInterval    time.Duration
+	PollMaxInterval time.Duration
+	PollBackoff     float64
+	// ReceiptTimeout gives up waiting for a receipt after this long, leaving
+	// the transaction in flight.
+	ReceiptTimeout time.Duration
+	// ReceiptOutputDir, when set, receives the transaction and receipt of
+	// every mined deposit as <pubkey>.json instead of stdout.
+	ReceiptOutputDir string
+	// TxSendBundle is the eth_sendBundle endpoint of a block builder the
+	// transactions are sent to as one bundle, instead of the node.
+	TxSendBundle string
+	// ValidUntilBlock, when set, is the last block a transaction may land in:
+	// nothing is sent or rebroadcast once the chain reached it.
+	ValidUntilBlock uint64
+	// MinConfirmationBlocks, when set, is the depth mined deposits have to
+	// reach before they count as confirmed.
+	MinConfirmationBlocks uint64
+	// TxpoolStatus reports the node's txpool state of every sent transaction.
+	TxpoolStatus bool
+	// MaxPendingTxs caps the transactions in flight with NoWait, 0 means no cap.
+	MaxPendingTxs int
+	// ChunkDelay is the least time between two broadcasts with NoWait.
+	ChunkDelay time.Duration
+
+	StateFile string
+	// LedgerDir holds the per-chain ledgers of deposited pubkeys, the user's
+	// config directory if empty. IgnoreLedger neither reads nor extends it.
+	LedgerDir    string
+	IgnoreLedger bool
+	// ResubmitFailed is a --summary-file of an earlier run, only its failed
+	// and reverted entries are submitted again.
+	ResubmitFailed string
+	PubkeyFilter   string
+	// AllowEmpty makes a deposit file without entries a successful no-op.
+	AllowEmpty bool
+	// FieldMap renames deposit file fields, e.g. "pubKeyHex=pubkey".
+	FieldMap string
+	// Dedupe drops entries repeating the pubkey and amount of an earlier entry.
+	Dedupe bool
+
+	// UnitsThreshold is the amount in gwei up to which an amount is assumed
+	// to be written in ETH by mistake; ConfirmUnits proceeds anyway.
+	UnitsThreshold uint64
+	ConfirmUnits   bool
+
+	// MaxTotal caps the summed amount of a batch in gwei; Force runs the
+	// batch anyway.
+	MaxTotal *big.Int
+	Force    bool
+	// AllowNetworkMismatch deposits entries whose network_name,
+	// fork_version or genesis_validators_root belong to another network than
+	// the node's. --force does not override these checks.
+	AllowNetworkMismatch bool
+
+	// PreviewCalldataHash prints the calldata hash of every deposit and exits.
+	PreviewCalldataHash bool
+
+	// PlanFromBlock is the first block searched for existing deposits by plan.
+	PlanFromBlock uint64
+
+	// DumpSigningData is a file receiving the signing data of every deposit.
+	DumpSigningData string
+
+	// WebhookURL receives a JSON POST before and after every deposit.
+	WebhookURL string
+	// HookErrorsFatal aborts the run when a submission hook fails.
+	HookErrorsFatal bool
+
+	// NotifyURL receives a batchSummary when the run completes and, with
+	// NotifyFailures, a failureNotice for every failed deposit.
+	NotifyURL      string
+	NotifyFailures bool
+
+	// IndexMap receives the deposit index of every successful deposit, as
+	// CSV if it ends in .csv and as JSON otherwise.
+	IndexMap string
+
+	// SummaryFile receives the final state of every entry as JSON, the
+	// printed summary and the file are ordered by SummarySort.
+	SummaryFile string
+	SummarySort string
+	// GasReport prints gas statistics of the mined transactions after the
+	// summary and adds them to SummaryFile.
+	GasReport bool
+
+	// MetricsAddr serves Prometheus metrics of the run on /metrics when set.
+	MetricsAddr string
+
+	// Output is outputText, or outputBundle to print the signed transactions
+	// as a txBundle on stdout, the human-readable output goes to stderr then.
+	Output string
+	// SignOnly signs every deposit with the nonce and fees of the node and
+	// prints the transactions as a txBundle instead of sending them, e.g.
+	// for the broadcast subcommand or a relay.
+	SignOnly bool
+
+	// OutputDir receives a timestamped directory per run holding the log,
+	// summary, other artifacts with relative paths and a run manifest.
+	OutputDir string
+
+	// LogFile receives JSON logs in addition to the console; LogRotate moves
+	// an existing file aside instead of appending to it.
+	LogFile   string
+	LogRotate bool
+
+	// Explorer prints block explorer links after each deposit, ExplorerURL
+	// replaces the transaction explorer of the network table.
+	Explorer    bool
+	ExplorerURL string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		EnvFile:         ".env",
+		HDPath:          defaultHDPath,
+		Signer:          signerKey,
+		DepositMethod:   defaultDepositMethod,
+		ExplorerAPIURL:  defaultExplorerAPIURL,
+		BatchSize:       1,
+		BatchMethod:     defaultBatchMethod,
+		GasLimit:        defaultGasLimit,
+		GasStrategy:     gasStrategyStandard,
+		ReceiptWorkers:  8,
+		MaxConnections:  16,
+		PollInterval:    time.Second,
+		PollMaxInterval: 2 * slotDuration,
+		PollBackoff:     1.5,
+		ReceiptTimeout:  30 * time.Minute,
+		NonceSource:     nonceSourcePending,
+		TxType:          txTypeAuto,
+		AccessList:      accessListNone,
+		SummarySort:     summarySortIndex,
+		Output:          outputText,
+		UnitsThreshold:  2048,
+	}
+}
+
+// RegisterFlags binds the command line flags to c.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.EnvFile, "env-file", c.EnvFile, "dotenv file with RPC_URL, PRIVATE_KEY and other settings, e.g. staging.env")
+	fs.StringVar(&c.MnemonicFile, "mnemonic-file", c.MnemonicFile, "derive the signing key from the BIP-39 mnemonic in this file instead of PRIVATE_KEY (passphrase: $MNEMONIC_PASSPHRASE)")
+	fs.StringVar(&c.HDPath, "hd-path", c.HDPath, "BIP-44 derivation path of the signing key with --mnemonic-file")
+	fs.StringVar(&c.Signer, "signer", c.Signer, "where the signing key is kept: key (PRIVATE_KEY or --mnemonic-file) or pkcs11 (an HSM, pin: $PKCS11_PIN)")
+	fs.StringVar(&c.PKCS11Module, "pkcs11-module", c.PKCS11Module, "path of the PKCS#11 module library with --signer pkcs11")
+	fs.UintVar(&c.PKCS11Slot, "pkcs11-slot", c.PKCS11Slot, "PKCS#11 slot of the signing key with --signer pkcs11")
+	fs.StringVar(&c.PKCS11KeyLabel, "pkcs11-key-label", c.PKCS11KeyLabel, "CKA_LABEL of the signing key with --signer pkcs11, if the token holds several keys")
+	fs.StringVar(&c.ExpectedFrom, "expected-from", c.ExpectedFrom, "refuse to run unless the signing key is for this address")
+	fs.StringVar(&c.ExpectedWithdrawalAddress, "expected-withdrawal-address", c.ExpectedWithdrawalAddress, "refuse entries whose 0x01/0x02 withdrawal credentials carry another address")
+	fs.StringVar(&c.DepositProfileFile, "deposit-profile", c.DepositProfileFile, "JSON network profile (chain ID, deposit contract, fork version, genesis validators root, explorers) added to or replacing the built-in networks")
+	fs.Var((*stringList)(&c.CrossVerify), "cross-verify", "also send every transaction to this RPC endpoint and compare its receipts with RPC_URL, repeat for more providers")
+	fs.Uint64Var(&c.ChainID, "chain-id", c.ChainID, "expected chain ID, abort if the node reports another one (0 = accept the node's)")
+	fs.StringVar(&c.ContractAddress, "contract", c.ContractAddress, "deposit contract address (default: $DEPOSIT_CONTRACT, then the network's deposit contract)")
+	fs.StringVar(&c.ConfirmContractAddress, "confirm-contract-address", c.ConfirmContractAddress, "confirm a custom deposit contract by repeating its address, instead of the prompt")
+	fs.StringVar(&c.ContractCodeHash, "contract-code-hash", c.ContractCodeHash, "expected keccak256 of the deposit contract code, as printed by a previous run")
+	fs.StringVar(&c.DepositMethod, "deposit-method", c.DepositMethod, "contract method to call, e.g. of a staking pool wrapper")
+	fs.Var((*stringList)(&c.DepositArgs), "deposit-arg", "value of an extra --deposit-method parameter, repeat in parameter order")
+	fs.BoolVar(&c.AbiFromExplorer, "abi-from-explorer", c.AbiFromExplorer, "fetch the verified contract ABI from the explorer API (key: $ETHERSCAN_API_KEY) and cache it")
+	fs.StringVar(&c.ExplorerAPIURL, "explorer-api-url", c.ExplorerAPIURL, "Etherscan compatible API used by --abi-from-explorer")
+	fs.IntVar(&c.BatchSize, "batch-size", c.BatchSize, "deposits per transaction, needs a wrapper contract with a batch deposit method")
+	fs.StringVar(&c.BatchMethod, "batch-method", c.BatchMethod, "batch deposit method taking (bytes[],bytes[],bytes[],bytes32[])")
+	fs.Uint64Var(&c.GasLimit, "gas-limit", c.GasLimit, "gas limit of each deposit, multiplied by the batch size for batches")
+	fs.IntVar(&c.ParallelGasEstimation, "parallel-gas-estimation", c.ParallelGasEstimation, "estimate the gas limit of every deposit up front with this many concurrent requests (0 = use --gas-limit)")
+	fs.StringVar(&c.GasStrategy, "gas-strategy", c.GasStrategy, "EIP-1559 fees from eth_feeHistory: economy, standard or fast")
+	fs.Var(gweiValue{&c.GasTipCap}, "gas-tip-cap", "max priority fee in gwei (default: --gas-strategy)")
+	fs.Var(gweiValue{&c.WarnTip}, "warn-tip-gwei", "warn loudly when the suggested tip is above this many gwei (default: 10 on mainnet, 100 on testnets; 0 = never)")
+	fs.Var(gweiValue{&c.GasFeeCap}, "gas-fee-cap", "max fee in gwei (default: --gas-strategy, node suggestion for legacy transactions)")
+	fs.StringVar(&c.NonceSource, "nonce-source", c.NonceSource, "nonce of the first deposit: pending (includes queued transactions) or latest (mined only)")
+	fs.BoolVar(&c.PrintNonces, "print-nonces", c.PrintNonces, "print the account's next nonce and its expected nonce after the batch (always shown by plan)")
+	fs.StringVar(&c.TxType, "tx-type", c.TxType, "transaction type: auto, dynamic (EIP-1559) or legacy")
+	fs.StringVar(&c.AccessList, "access-list", c.AccessList, "attach an access list from eth_createAccessList: auto or none")
+	fs.Float64Var(&c.RPS, "rps", c.RPS, "max RPC requests per second (0 = unlimited)")
+	fs.IntVar(&c.MaxConnections, "max-connections", c.MaxConnections, "max HTTP connections to the node, requests beyond it wait for a free one (0 = unlimited)")
+	fs.BoolVar(&c.Interactive, "interactive", c.Interactive, "review, exclude and change entries before submitting")
+	fs.BoolVar(&c.Yes, "yes", c.Yes, "do not ask for any confirmation")
+	fs.DurationVar(&c.ConfirmTimeout, "confirm-timeout", c.ConfirmTimeout, "cancel when a confirmation is not answered within this duration, e.g. 2m (0 = wait forever)")
+	fs.BoolVar(&c.ConfirmDetails, "confirm-details", c.ConfirmDetails, "show the full transaction JSON with every confirmation")
+	fs.BoolVar(&c.PrintDepositSummaryBefore, "print-deposit-summary-before", c.PrintDepositSummaryBefore, "print the table of all entries to submit before the first confirmation, also with --yes")
+	fs.BoolVar(&c.InteractiveGas, "interactive-gas", c.InteractiveGas, "approve the fees before each transaction, or fetch them again to wait out a fee spike (skipped with --yes)")
+	fs.BoolVar(&c.ConfirmEachWithDiff, "confirm-each-with-diff", c.ConfirmEachWithDiff, "show only the fields of each entry that differ from the previously confirmed one, changes other than the pubkey highlighted")
+	fs.Uint64Var(&c.StartAtBlock, "start-at-block", c.StartAtBlock, "wait until the chain reaches this block before submitting")
+	fs.Func("start-at-time", "wait until the latest block timestamp reaches this RFC3339 time before submitting", func(s string) error {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		c.StartAtTime = t
+		return nil
+	})
+	fs.BoolVar(&c.Simulate, "simulate", c.Simulate, "simulate each deposit with eth_call before confirming it")
+	fs.BoolVar(&c.SimulateAll, "simulate-all", c.SimulateAll, "simulate every deposit with eth_call before sending any, abort if one fails")
+	fs.BoolVar(&c.AbortOnInvalid, "abort-on-invalid", c.AbortOnInvalid, "stop at the first entry with invalid deposit data instead of skipping it")
+	fs.BoolVar(&c.IgnoreRevert, "ignore-revert", c.IgnoreRevert, "continue past reverted deposits instead of stopping with an error")
+	fs.BoolVar(&c.VerifyAfterSubmit, "verify-after-submit", c.VerifyAfterSubmit, "verify the DepositEvent and deposit count of each mined deposit")
+	fs.BoolVar(&c.NoWait, "no-wait", c.NoWait, "send all deposits first, then wait for the receipts")
+	fs.StringVar(&c.ReceiptOutputDir, "receipt-output-dir", c.ReceiptOutputDir, "save the transaction and receipt of every mined deposit to <dir>/<pubkey>.json instead of printing the receipt")
+	fs.StringVar(&c.TxSendBundle, "tx-send-bundle", c.TxSendBundle, "send all transactions as one atomic bundle to this eth_sendBundle endpoint of a block builder, e.g. https://relay.flashbots.net")
+	fs.Uint64Var(&c.ValidUntilBlock, "valid-until-block", c.ValidUntilBlock, "do not send or rebroadcast any transaction once the chain reached this block (0 = no deadline)")
+	fs.Uint64Var(&c.MinConfirmationBlocks, "min-confirmation-blocks", c.MinConfirmationBlocks, "wait until mined deposits are this many blocks deep before they count as confirmed, e.g. 64 for two epochs (0 = once mined)")
+	fs.BoolVar(&c.TxpoolStatus, "txpool-status", c.TxpoolStatus, "report whether each sent transaction is pending or queued in the node's txpool")
+	fs.IntVar(&c.MaxPendingTxs, "max-pending-txs", c.MaxPendingTxs, "with --no-wait, wait for a confirmation when this many transactions are pending (0 = unlimited)")
+	fs.DurationVar(&c.ChunkDelay, "chunk-delay", c.ChunkDelay, "with --no-wait, leave at least this much time between two broadcasts, for providers with anti-spam limits")
+	fs.DurationVar(&c.PollInterval, "poll-interval", c.PollInterval, "first interval of polling for receipts and confirmations")
+	fs.DurationVar(&c.PollMaxInterval, "poll-max-interval", c.PollMaxInterval, "longest interval of polling for receipts and confirmations")
+	fs.DurationVar(&c.ReceiptTimeout, "receipt-timeout", c.ReceiptTimeout, "stop waiting for the receipt of a transaction after this duration, leaving it in flight (0 = wait forever)")
+	fs.Float64Var(&c.PollBackoff, "poll-backoff", c.PollBackoff, "factor the polling interval grows by with every poll, up to --poll-max-interval")
+	fs.IntVar(&c.ReceiptWorkers, "receipt-workers", c.ReceiptWorkers, "max receipts fetched concurrently with --no-wait")
+	fs.StringVar(&c.StateFile, "state-file", c.StateFile, "record deposit outcomes in this file and skip confirmed deposits on re-run")
+	fs.StringVar(&c.LedgerDir, "ledger-dir", c.LedgerDir, "directory of the per-chain ledgers of deposited pubkeys (default: the user config directory)")
+	fs.BoolVar(&c.IgnoreLedger, "ignore-ledger", c.IgnoreLedger, "deposit pubkeys the ledger already has, e.g. for top-ups, and do not record this run")
+	fs.StringVar(&c.ResubmitFailed, "resubmit-failed", c.ResubmitFailed, "only submit the entries that failed or reverted in this --summary-file of an earlier run, and update it")
+	fs.StringVar(&c.PubkeyFilter, "pubkey-filter", c.PubkeyFilter, "only submit deposits for these pubkeys (comma separated, or a file with one pubkey per line)")
+	fs.BoolVar(&c.AllowEmpty, "allow-empty", c.AllowEmpty, "exit successfully if the deposit file has no entries")
+	fs.StringVar(&c.FieldMap, "field-map", c.FieldMap, "rename deposit file fields, e.g. pubKeyHex=pubkey,wc=withdrawal_credentials")
+	fs.BoolVar(&c.Dedupe, "dedupe", c.Dedupe, "drop entries that repeat the pubkey and amount of an earlier entry")
+	fs.Uint64Var(&c.UnitsThreshold, "units-threshold", c.UnitsThreshold, "amounts up to this many gwei are treated as written in ETH by mistake")
+	fs.BoolVar(&c.ConfirmUnits, "confirm-units", c.ConfirmUnits, "submit amounts below --units-threshold as gwei anyway")
+	fs.Var(ethValue{&c.MaxTotal}, "max-total-eth", "refuse to run if the deposits add up to more than this many ETH")
+	fs.BoolVar(&c.Force, "force", c.Force, "run even if a safety check such as --max-total-eth trips")
+	fs.BoolVar(&c.AllowNetworkMismatch, "allow-network-mismatch", c.AllowNetworkMismatch, "deposit entries generated for another network than the node's")
+	fs.BoolVar(&c.PreviewCalldataHash, "preview-calldata-hash", c.PreviewCalldataHash, "print the keccak256 of every deposit's calldata and exit")
+	fs.Uint64Var(&c.PlanFromBlock, "plan-from-block", c.PlanFromBlock, "first block searched for existing deposits by plan, e.g. the contract deployment block")
+	fs.StringVar(&c.DumpSigningData, "dump-signing-data", c.DumpSigningData, "write the deposit message root, domain and signing root of every deposit to this file")
+	fs.StringVar(&c.WebhookURL, "webhook-url", c.WebhookURL, "POST a JSON event to this URL before and after every deposit")
+	fs.BoolVar(&c.HookErrorsFatal, "webhook-fatal", c.HookErrorsFatal, "abort when the webhook fails instead of only logging it")
+	fs.StringVar(&c.NotifyURL, "notify-url", c.NotifyURL, "POST a batch summary to this webhook (e.g. Slack) when the run completes")
+	fs.BoolVar(&c.NotifyFailures, "notify-failures", c.NotifyFailures, "also notify --notify-url about every failed deposit")
+	fs.StringVar(&c.IndexMap, "index-map", c.IndexMap, "write pubkey, deposit index, tx hash and block of every deposit to this JSON or .csv file")
+	fs.StringVar(&c.SummaryFile, "summary-file", c.SummaryFile, "write the final state of every entry to this JSON file")
+	fs.BoolVar(&c.GasReport, "gas-report", c.GasReport, "print min, max and average gas used and gas price of the mined transactions, also in --summary-file")
+	fs.StringVar(&c.SummarySort, "summary-sort", c.SummarySort, "order of the final summary: index or status")
+	fs.StringVar(&c.MetricsAddr, "metrics-addr", c.MetricsAddr, "serve Prometheus metrics on this address, e.g. :9100")
+	fs.StringVar(&c.Output, "output", c.Output, "output format: text, or bundle to print the signed transactions as JSON (messages go to stderr)")
+	fs.BoolVar(&c.SignOnly, "sign-only", c.SignOnly, "sign every deposit and print the transactions as --output bundle without sending them, e.g. for broadcast or a relay")
+	fs.StringVar(&c.OutputDir, "output-dir", c.OutputDir, "write the log, summary, manifest and other relative artifact paths of each run to a timestamped subdirectory")
+	fs.StringVar(&c.LogFile, "log-file", c.LogFile, "also write JSON logs to this file")
+	fs.BoolVar(&c.LogRotate, "log-rotate", c.LogRotate, "rotate an existing --log-file instead of appending to it")
+	fs.BoolVar(&c.Explorer, "explorer", c.Explorer, "print block explorer links after each deposit")
+	fs.StringVar(&c.ExplorerURL, "explorer-url", c.ExplorerURL, "transaction explorer base URL, implies --explorer")
+}
+
+// LoadEnv reads the settings that are taken from the environment.
+func (c *Config) LoadEnv() {
+	c.RPCURL = os.Getenv("RPC_URL")
+	c.PrivateKey = os.Getenv("PRIVATE_KEY")
+	c.PKCS11Pin = os.Getenv("PKCS11_PIN")
+	if c.ContractAddress == "" {
+		c.ContractAddress = os.Getenv("DEPOSIT_CONTRACT")
+	}
+	c.ExplorerAPIKey = os.Getenv("ETHERSCAN_API_KEY")
+}
+
+func (c Config) Validate() error {
+	if err := c.validateSigner(); err != nil {
+		return err
+	}
+	if c.ExpectedFrom != "" && !common.IsHexAddress(c.ExpectedFrom) {
+		return fmt.Errorf("invalid --expected-from address %q", c.ExpectedFrom)
+	}
+	if c.ExpectedWithdrawalAddress != "" && !common.IsHexAddress(c.ExpectedWithdrawalAddress) {
+		return fmt.Errorf("invalid --expected-withdrawal-address %q", c.ExpectedWithdrawalAddress)
+	}
+	if c.RPCURL == "" {
+		return errors.New("RPC_URL is not set")
+	}
+	if c.ContractAddress != "" && !common.IsHexAddress(c.ContractAddress) {
+		return fmt.Errorf("invalid contract address %q", c.ContractAddress)
+	}
+	if c.AbiFromExplorer && c.PreviewCalldataHash {
+		return errors.New("--preview-calldata-hash works offline and cannot be combined with --abi-from-explorer")
+	}
+	if c.ConfirmContractAddress != "" && !common.IsHexAddress(c.ConfirmContractAddress) {
+		return fmt.Errorf("invalid --confirm-contract-address %q", c.ConfirmContractAddress)
+	}
+	if c.ContractCodeHash != "" && !isHexHash(c.ContractCodeHash) {
+		return fmt.Errorf("invalid contract code hash %q, expected 0x and 32 bytes of hex", c.ContractCodeHash)
+	}
+	if c.GasLimit == 0 {
+		return errors.New("gas limit must be positive")
+	}
+	if c.BatchSize < 1 {
+		return errors.New("batch size must be positive")
+	}
+	if c.BatchSize > 1 && c.VerifyAfterSubmit {
+		return errors.New("--verify-after-submit checks one deposit per transaction and cannot be combined with --batch-size")
+	}
+	if c.VerifyAfterSubmit && c.DepositMethod != defaultDepositMethod && c.ChainID != 0 {
+		if _, ok := networks[c.ChainID]; !ok {
+			return fmt.Errorf("--verify-after-submit checks the deposit contract behind --deposit-method %s, and none is known for chain ID %d", c.DepositMethod, c.ChainID)
+		}
+	}
+	if c.ParallelGasEstimation < 0 {
+		return errors.New("parallel gas estimation must not be negative")
+	}
+	if c.ParallelGasEstimation > 0 && c.BatchSize > 1 {
+		return errors.New("--parallel-gas-estimation estimates single deposits and cannot be combined with --batch-size")
+	}
+	if _, ok := gasStrategies[c.GasStrategy]; !ok {
+		return fmt.Errorf("invalid gas strategy %q, expected one of %s", c.GasStrategy, gasStrategyNames())
+	}
+	if c.TxType != txTypeAuto && c.TxType != txTypeDynamic && c.TxType != txTypeLegacy {
+		return fmt.Errorf("invalid transaction type %q, expected %s, %s or %s", c.TxType, txTypeAuto, txTypeDynamic, txTypeLegacy)
+	}
+	if c.NonceSource != nonceSourcePending && c.NonceSource != nonceSourceLatest {
+		return fmt.Errorf("invalid nonce source %q, expected %s or %s", c.NonceSource, nonceSourcePending, nonceSourceLatest)
+	}
+	if c.AccessList != accessListNone && c.AccessList != accessListAuto {
+		return fmt.Errorf("invalid access list mode %q, expected %s or %s", c.AccessList, accessListAuto, accessListNone)
+	}
+	if c.RPS < 0 {
+		return errors.New("rps must not be negative")
+	}
+	if c.MaxConnections < 0 {
+		return errors.New("max connections must not be negative")
+	}
+	if c.MaxPendingTxs < 0 {
+		return errors.New("max pending txs must not be negative")
+	}
+	if c.ChunkDelay < 0 {
+		return errors.New("chunk delay must not be negative")
+	}
+	if c.ChunkDelay > 0 && !c.NoWait {
+		return errors.New("--chunk-delay paces the broadcasts of --no-wait, without it every deposit already waits for its receipt")
+	}
+	if c.ReceiptWorkers < 1 {
+		return errors.New("receipt workers must be positive")
+	}
+	if err := c.validatePolling(); err != nil {
+		return err
+	}
+	if c.TxSendBundle != "" && c.StateFile != "" {
+		return errors.New("--tx-send-bundle cannot be combined with --state-file, a resumed run would send the transactions of the bundle one by one")
+	}
+	if c.TxSendBundle != "" && len(c.CrossVerify) > 0 {
+		return errors.New("--tx-send-bundle keeps the transactions private until they are included and cannot be combined with --cross-verify")
+	}
+	if c.TxSendBundle != "" && c.ChunkDelay > 0 {
+		return errors.New("--tx-send-bundle sends all transactions at once and cannot be combined with --chunk-delay")
+	}
+	if c.TxSendBundle != "" && c.MaxPendingTxs > 0 {
+		return errors.New("--tx-send-bundle sends all transactions at once and cannot be combined with --max-pending-txs")
+	}
+	if c.SignOnly && c.TxSendBundle != "" {
+		return errors.New("--sign-only sends nothing and cannot be combined with --tx-send-bundle")
+	}
+	if c.SignOnly && c.StateFile != "" {
+		return errors.New("--sign-only cannot be combined with --state-file, a re-run would send the signed transactions")
+	}
+	if c.ConfirmTimeout < 0 {
+		return errors.New("confirm timeout must not be negative")
+	}
+	if c.Interactive && c.Yes {
+		return errors.New("--interactive and --yes are mutually exclusive")
+	}
+	if c.SummarySort != summarySortIndex && c.SummarySort != summarySortStatus {
+		return fmt.Errorf("invalid summary sort %q, expected %s or %s", c.SummarySort, summarySortIndex, summarySortStatus)
+	}
+	if c.Output != outputText && c.Output != outputBundle {
+		return fmt.Errorf("invalid output %q, expected %s or %s", c.Output, outputText, outputBundle)
+	}
+	if c.GasTipCap != nil && c.GasFeeCap != nil && c.GasTipCap.Cmp(c.GasFeeCap) > 0 {
+		return fmt.Errorf("gas tip cap %s wei exceeds gas fee cap %s wei", c.GasTipCap, c.GasFeeCap)
+	}
+	return nil
+}
+
+// ValidateBroadcast checks the settings used by the broadcast subcommand,
+// which needs no private key.
+func (c Config) ValidateBroadcast() error {
+	if c.RPCURL == "" {
+		return errors.New("RPC_URL is not set")
+	}
+	if c.RPS < 0 {
+		return errors.New("rps must not be negative")
+	}
+	if c.MaxConnections < 0 {
+		return errors.New("max connections must not be negative")
+	}
+	return c.validatePolling()
+}
+
+func (c Config) validateSigner() error {
+	switch c.Signer {
+	case signerKey:
+		if c.PrivateKey == "" && c.MnemonicFile == "" {
+			return errors.New("PRIVATE_KEY is not set")
+		}
+		if c.PrivateKey != "" && c.MnemonicFile != "" {
+			return errors.New("PRIVATE_KEY and --mnemonic-file are both set, use one signing key")
+		}
+	case signerPKCS11:
+		if c.PKCS11Module == "" {
+			return errors.New("--signer pkcs11 needs --pkcs11-module")
+		}
+		if c.PKCS11Pin == "" {
+			return errors.New("PKCS11_PIN is not set")
+		}
+		if c.PrivateKey != "" || c.MnemonicFile != "" {
+			return errors.New("--signer pkcs11 signs with the HSM, unset PRIVATE_KEY and --mnemonic-file")
+		}
+	default:
+		return fmt.Errorf("invalid --signer %q, expected %s or %s", c.Signer, signerKey, signerPKCS11)
+	}
+	return nil
+}
+
+func (c Config) validatePolling() error {
+	if c.PollInterval <= 0 {
+		return errors.New("poll interval must be positive")
+	}
+	if c.PollMaxInterval < c.PollInterval {
+		return errors.New("poll max interval must not be shorter than the poll interval")
+	}
+	if c.PollBackoff < 1 {
+		return errors.New("poll backoff must be at least 1")
+	}
+	if c.ReceiptTimeout < 0 {
+		return errors.New("receipt timeout must not be negative")
+	}
+	return nil
+}
+
+// Poll is the polling policy of receipt and confirmation waits.
+func (c Config) Poll() pollPolicy {
+	return pollPolicy{Min: c.PollInterval, Max: c.PollMaxInterval, Factor: c.PollBackoff, ReceiptTimeout: c.ReceiptTimeout}
+}
+
+// ResolveContract falls back to the deposit contract of the network when no
+// contract address is configured, and reports whether the address is custom,
+// i.e. not the known deposit contract of the network.
+func (c *Config) ResolveContract(n network, knownNetwork bool) bool {
+	if c.ContractAddress == "" {
+		if knownNetwork {
+			c.ContractAddress = n.DepositContract.Hex()
+		} else {
+			c.ContractAddress = defaultContractAddress
+		}
+		return false
+	}
+	return !knownNetwork || c.DepositAddress() != n.DepositContract
+}
+
+func (c Config) DepositAddress() common.Address {
+	return common.HexToAddress(c.ContractAddress)
+}
+
+// gweiValue is a flag.Value for an optional amount given in (possibly
+// fractional) gwei and stored in wei.
+type gweiValue struct {
+	wei **big.Int
+}
+
+func (g gweiValue) String() string {
+	if g.wei == nil || *g.wei == nil {
+		return ""
+	}
+	return new(big.Rat).SetFrac(*g.wei, big.NewInt(1e9)).FloatString(9)
+}
+
+func (g gweiValue) Set(s string) error {
+	wei, err := parseGwei(s)
+	if err != nil {
+		return err
+	}
+	*g.wei = wei
+	return nil
+}
+
+// ethValue is a flag.Value for an optional amount given in (possibly
+// fractional) ETH and stored in gwei, the unit of deposit amounts.
+type ethValue struct {
+	gwei **big.Int
+}
+
+func (e ethValue) String() string {
+	if e.gwei == nil || *e.gwei == nil {
+		return ""
+	}
+	return formatGweiAsETH(*e.gwei)
+}
+
+func (e ethValue) Set(s string) error {
+	eth, ok := new(big.Rat).SetString(s)
+	if !ok || eth.Sign() < 0 {
+		return fmt.Errorf("invalid ETH amount %q", s)
+	}
+	gwei := eth.Mul(eth, new(big.Rat).SetInt(gweiPerETH))
+	if !gwei.IsInt() {
+		return fmt.Errorf("ETH amount %q has more than 9 decimals", s)
+	}
+	*e.gwei = new(big.Int).Set(gwei.Num())
+	return nil
+}
+
+// stringList is a flag.Value collecting every occurrence of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
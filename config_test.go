@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig is DefaultConfig with the settings every run needs.
+func validConfig() Config {
+	cfg := DefaultConfig()
+	cfg.RPCURL = "http://127.0.0.1:8545"
+	cfg.PrivateKey = testPrivateKey
+	return cfg
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(cfg *Config)
+		err    string
+	}{
+		{"private key and mnemonic", func(cfg *Config) { cfg.MnemonicFile = "mnemonic.txt" }, "PRIVATE_KEY and --mnemonic-file are both set"},
+		{"no RPC_URL", func(cfg *Config) { cfg.RPCURL = "" }, "RPC_URL is not set"},
+		{"tip above fee cap", func(cfg *Config) {
+			cfg.GasTipCap, cfg.GasFeeCap = big.NewInt(3_000_000_000), big.NewInt(2_000_000_000)
+		}, "gas tip cap 3000000000 wei exceeds gas fee cap 2000000000 wei"},
+		{"invalid contract", func(cfg *Config) { cfg.ContractAddress = "0x1234" }, `invalid contract address "0x1234"`},
+		{"invalid expected from", func(cfg *Config) { cfg.ExpectedFrom = "me" }, `invalid --expected-from address "me"`},
+		{"invalid code hash", func(cfg *Config) { cfg.ContractCodeHash = "0xabcd" }, "invalid contract code hash"},
+		{"zero gas limit", func(cfg *Config) { cfg.GasLimit = 0 }, "gas limit must be positive"},
+		{"zero batch size", func(cfg *Config) { cfg.BatchSize = 0 }, "batch size must be positive"},
+		{"batch with verification", func(cfg *Config) { cfg.BatchSize, cfg.VerifyAfterSubmit = 10, true }, "cannot be combined with --batch-size"},
+		{"wrapper verification on an unknown network", func(cfg *Config) {
+			cfg.VerifyAfterSubmit, cfg.DepositMethod, cfg.ChainID = true, "depositFor", 1337
+		}, "none is known for chain ID 1337"},
+		{"unknown gas strategy", func(cfg *Config) { cfg.GasStrategy = "cheapest" }, `invalid gas strategy "cheapest"`},
+		{"unknown tx type", func(cfg *Config) { cfg.TxType = "blob" }, `invalid transaction type "blob"`},
+		{"negative rps", func(cfg *Config) { cfg.RPS = -1 }, "rps must not be negative"},
+		{"chunk delay without no-wait", func(cfg *Config) { cfg.ChunkDelay = time.Second }, "--chunk-delay paces the broadcasts of --no-wait"},
+		{"bundle with state file", func(cfg *Config) { cfg.TxSendBundle, cfg.StateFile = "https://relay.example", "state.json" }, "--tx-send-bundle cannot be combined with --state-file"},
+		{"sign-only with state file", func(cfg *Config) { cfg.SignOnly, cfg.StateFile = true, "state.json" }, "--sign-only cannot be combined with --state-file"},
+		{"interactive with yes", func(cfg *Config) { cfg.Interactive, cfg.Yes = true, true }, "mutually exclusive"},
+		{"poll max below interval", func(cfg *Config) { cfg.PollMaxInterval = cfg.PollInterval / 2 }, "poll max interval must not be shorter"},
+		{"negative receipt timeout", func(cfg *Config) { cfg.ReceiptTimeout = -time.Second }, "receipt timeout must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.change(&cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestConfigFlags(t *testing.T) {
+	cfg := DefaultConfig()
+	fs := flag.NewFlagSet("go-deposit", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	err := fs.Parse([]string{"--gas-tip-cap", "1.5", "--gas-limit", "120000", "--batch-size", "4", "--receipt-timeout", "5m", "--yes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GasTipCap == nil || cfg.GasTipCap.Cmp(big.NewInt(1_500_000_000)) != 0 {
+		t.Errorf("--gas-tip-cap 1.5 is %v wei, want 1500000000", cfg.GasTipCap)
+	}
+	if cfg.GasLimit != 120000 || cfg.BatchSize != 4 || cfg.ReceiptTimeout != 5*time.Minute || !cfg.Yes {
+		t.Errorf("flags parsed to gas limit %d, batch size %d, receipt timeout %s, yes %v", cfg.GasLimit, cfg.BatchSize, cfg.ReceiptTimeout, cfg.Yes)
+	}
+	// Flags left out keep their defaults
+	if cfg.PollInterval != DefaultConfig().PollInterval || cfg.GasFeeCap != nil {
+		t.Errorf("poll interval %s and fee cap %v, want the defaults", cfg.PollInterval, cfg.GasFeeCap)
+	}
+}
+
+// TestLoadEnvWithoutDotenv configures and runs the package without any .env
+// file, and with one in the working directory that it must not read.
+func TestLoadEnvWithoutDotenv(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := newFakeNode(t, holeskyChainID)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("RPC_URL", "")
+	t.Setenv("PRIVATE_KEY", "")
+
+	cfg := DefaultConfig()
+	cfg.LoadEnv()
+	if cfg.RPCURL != "" || cfg.PrivateKey != "" {
+		t.Fatalf("LoadEnv read RPC_URL %q without .env", cfg.RPCURL)
+	}
+	submitter := node.submitter(t, &cfg)
+	if _, err := submitter.Submit(testDepositData(t, 0x11, 32_000_000_000)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("RPC_URL=http://127.0.0.1:1\nPRIVATE_KEY="+testPrivateKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg = DefaultConfig()
+	cfg.LoadEnv()
+	if cfg.RPCURL != "" || cfg.PrivateKey != "" {
+		t.Errorf("LoadEnv read .env: RPC_URL %q", cfg.RPCURL)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// confirmedEntry is the last deposit the operator confirmed, which
+// --confirm-each-with-diff compares the next one with.
+type confirmedEntry struct {
+	data DepositData
+	to   common.Address
+}
+
+// boxField is a line of the deposit box that --confirm-each-with-diff shows
+// only when it changed. Pubkeys differ between entries by design, a change
+// of any other field is highlighted.
+type boxField struct {
+	label, value string
+	expected     bool
+}
+
+func depositFieldsOf(data DepositData) []boxField {
+	withdrawal := "none, BLS credentials"
+	if address, ok := withdrawalAddress(data.WithdrawalCredentials); ok {
+		withdrawal = address.Hex()
+	}
+	return []boxField{
+		{label: "Pubkey", value: "0x" + data.PubKey.String(), expected: true},
+		{label: "Credentials", value: "0x" + data.WithdrawalCredentials.String()},
+		{label: "Withdrawal", value: withdrawal},
+		{label: "Amount", value: formatGweiAsETH(&data.Amount) + " ETH"},
+	}
+}
+
+// fieldLines renders fields, with prev only those that differ from prev.
+func fieldLines(fields, prev []boxField) []string {
+	var lines, same []string
+	for i, f := range fields {
+		line := fmt.Sprintf("  %-14s%s", f.label+":", f.value)
+		switch {
+		case prev == nil:
+		case prev[i].value == f.value:
+			same = append(same, strings.ToLower(f.label))
+			continue
+		case !f.expected:
+			line = fmt.Sprintf("! %-14s%s  <-- CHANGED, was %s", f.label+":", f.value, prev[i].value)
+		}
+		lines = append(lines, line)
+	}
+	if len(same) > 0 {
+		lines = append(lines, "  ("+strings.Join(same, ", ")+" as before)")
+	}
+	return lines
+}
+
+// depositBox renders the fields operators check before confirming a
+// transaction in a box, one section per deposit. With diff, a deposit shows
+// only the fields that differ from the one before it, in tx or prev, the
+// previously confirmed entry; the first one is shown in full.
+func depositBox(deposits []DepositData, tx *types.Transaction, diff bool, prev *confirmedEntry) string {
+	var lines []string
+	for i, data := range deposits {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		var before []boxField
+		if diff && i > 0 {
+			before = depositFieldsOf(deposits[i-1])
+		} else if diff && prev != nil {
+			before = depositFieldsOf(prev.data)
+		}
+		lines = append(lines, fmt.Sprintf("Deposit %d", data.index))
+		lines = append(lines, fieldLines(depositFieldsOf(data), before)...)
+	}
+	to := fmt.Sprintf("To:             %s", tx.To().Hex())
+	if diff && prev != nil && prev.to != *tx.To() {
+		to = fmt.Sprintf("! To:           %s  <-- CHANGED, was %s", tx.To().Hex(), prev.to.Hex())
+	}
+	maxFee := new(big.Int).Sub(tx.Cost(), tx.Value())
+	lines = append(lines, "",
+		to,
+		fmt.Sprintf("Nonce:          %d", tx.Nonce()),
+		fmt.Sprintf("Max fee:        %s ETH", formatWeiAsETH(maxFee)),
+		fmt.Sprintf("Max total cost: %s ETH", formatWeiAsETH(tx.Cost())),
+		fmt.Sprintf("Calldata hash:  %s", crypto.Keccak256Hash(tx.Data()).Hex()),
+	)
+
+	width := 0
+	for _, line := range lines {
+		width = max(width, utf8.RuneCountInString(line))
+	}
+	var b strings.Builder
+	b.WriteString("┌" + strings.Repeat("─", width+2) + "┐\n")
+	for _, line := range lines {
+		b.WriteString("│ " + line + strings.Repeat(" ", width-utf8.RuneCountInString(line)) + " │\n")
+	}
+	b.WriteString("└" + strings.Repeat("─", width+2) + "┘\n")
+	return b.String()
+}
+
+// confirmContract asks the operator to type a custom deposit contract
+// address, as funds sent to the wrong contract are lost. --confirm-contract-address
+// with the same address confirms it up front, as does --yes.
+func confirmContract(cfg Config, address common.Address, codeHash common.Hash, depositCount uint64) bool {
+	if cfg.ConfirmContractAddress != "" {
+		if common.HexToAddress(cfg.ConfirmContractAddress) != address {
+			fmt.Printf("--confirm-contract-address %s does not match the deposit contract %s\n", cfg.ConfirmContractAddress, address.Hex())
+			return false
+		}
+		return true
+	}
+	if cfg.Yes {
+		return true
+	}
+	fmt.Printf("\n%s is not the deposit contract of this network.\n", address.Hex())
+	fmt.Printf("  Code:      present, hash %s\n", codeHash.Hex())
+	fmt.Printf("  Deposits:  get_deposit_count() returns %d\n", depositCount)
+	fmt.Printf("Type the contract address to deposit to it: ")
+	answer, answered := readLineTimeout(cfg.ConfirmTimeout)
+	if !answered {
+		fmt.Printf("\nNo answer within %s\n", cfg.ConfirmTimeout)
+		return false
+	}
+	return common.IsHexAddress(answer) && common.HexToAddress(answer) == address
+}
+
+func printTransactionJSON(tx *types.Transaction) {
+	txJS, err := json.MarshalIndent(tx, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to marshal transaction: %v", err)
+	}
+	fmt.Printf("Transaction: %s\n\n", string(txJS))
+}
+
+// confirmTransaction shows the deposits of tx and, unless --yes, asks the
+// operator to confirm them, an answer not given within --confirm-timeout
+// cancels. "d" or --confirm-details show the full
+// transaction JSON as well, --confirm-each-with-diff only what changed
+// since the previous confirmation.
+func (s *Submitter) confirmTransaction(deposits []DepositData, tx *types.Transaction) bool {
+	if s.cfg.ConfirmDetails {
+		printTransactionJSON(tx)
+	}
+	fmt.Print(depositBox(deposits, tx, s.cfg.ConfirmEachWithDiff, s.lastConfirmed))
+	if s.cfg.Yes {
+		s.confirmed(deposits, tx)
+		return true
+	}
+	for {
+		fmt.Printf("Confirm transaction? (y/n, d = show details): ")
+		answer, answered := readLineTimeout(s.cfg.ConfirmTimeout)
+		if !answered {
+			fmt.Printf("\nNo answer within %s\n", s.cfg.ConfirmTimeout)
+			return false
+		}
+		switch answer {
+		case "y":
+			s.confirmed(deposits, tx)
+			return true
+		case "d":
+			printTransactionJSON(tx)
+		default:
+			return false
+		}
+	}
+}
+
+// confirmed remembers the last deposit of tx for --confirm-each-with-diff.
+func (s *Submitter) confirmed(deposits []DepositData, tx *types.Transaction) {
+	s.lastConfirmed = &confirmedEntry{data: deposits[len(deposits)-1], to: *tx.To()}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// depositContractABI holds the views and the DepositEvent of the beacon
+// deposit contract, so that the health checks and the verification of mined
+// deposits need neither abi.json nor the ABI of a wrapper.
+var depositContractABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(`[
+		{"inputs": [], "name": "get_deposit_count", "outputs": [{"name": "", "type": "bytes"}], "stateMutability": "view", "type": "function"},
+		{"inputs": [], "name": "get_deposit_root", "outputs": [{"name": "", "type": "bytes32"}], "stateMutability": "view", "type": "function"},
+		{"anonymous": false, "inputs": [
+			{"indexed": false, "name": "pubkey", "type": "bytes"},
+			{"indexed": false, "name": "withdrawal_credentials", "type": "bytes"},
+			{"indexed": false, "name": "amount", "type": "bytes"},
+			{"indexed": false, "name": "signature", "type": "bytes"},
+			{"indexed": false, "name": "index", "type": "bytes"}
+		], "name": "DepositEvent", "type": "event"}
+	]`))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// depositCheckAddress returns the contract the deposit contract checks run
+// on: the deposit address, or for a wrapper called with --deposit-method the
+// deposit contract of the network behind it. ok is false for a wrapper on an
+// unknown network, whose deposit contract cannot be checked.
+func depositCheckAddress(cfg Config, n network, knownNetwork bool) (address common.Address, ok bool) {
+	if cfg.DepositMethod == defaultDepositMethod {
+		return cfg.DepositAddress(), true
+	}
+	return n.DepositContract, knownNetwork
+}
+
+// readDepositRoot calls get_deposit_root() on the contract. A contract that
+// has no code or does not implement the method is not a deposit contract.
+func readDepositRoot(ctx context.Context, client *ethclient.Client, address common.Address) (common.Hash, error) {
+	code, err := client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get code at %s: %w", address.Hex(), err)
+	}
+	if len(code) == 0 {
+		return common.Hash{}, fmt.Errorf("no contract code at %s", address.Hex())
+	}
+
+	input, err := depositContractABI.Pack("get_deposit_root")
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to pack get_deposit_root: %w", err)
+	}
+
+	output, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: input}, nil)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("get_deposit_root() reverted on %s: %w", address.Hex(), err)
+	}
+
+	values, err := depositContractABI.Unpack("get_deposit_root", output)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("unexpected get_deposit_root() result from %s: %w", address.Hex(), err)
+	}
+	root, ok := values[0].([32]byte)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("unexpected get_deposit_root() result type %T", values[0])
+	}
+	return common.Hash(root), nil
+}
+
+// readDepositCount calls get_deposit_count() at the given block, nil meaning latest.
+func readDepositCount(ctx context.Context, client *ethclient.Client, address common.Address, block *big.Int) (uint64, error) {
+	input, err := depositContractABI.Pack("get_deposit_count")
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack get_deposit_count: %w", err)
+	}
+
+	output, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: input}, block)
+	if err != nil {
+		return 0, fmt.Errorf("get_deposit_count() failed on %s: %w", address.Hex(), err)
+	}
+
+	values, err := depositContractABI.Unpack("get_deposit_count", output)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected get_deposit_count() result from %s: %w", address.Hex(), err)
+	}
+	count, ok := values[0].([]byte)
+	if !ok || len(count) != 8 {
+		return 0, fmt.Errorf("unexpected get_deposit_count() result %v", values[0])
+	}
+	return binary.LittleEndian.Uint64(count), nil
+}
+
+// codeHashAt returns the keccak256 of the code at address, which must have
+// some.
+func codeHashAt(ctx context.Context, client *ethclient.Client, address common.Address) (common.Hash, error) {
+	code, err := client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get code at %s: %w", address.Hex(), err)
+	}
+	if len(code) == 0 {
+		return common.Hash{}, fmt.Errorf("no contract code at %s", address.Hex())
+	}
+	return crypto.Keccak256Hash(code), nil
+}
+
+// checkDepositContract makes sure the contract behaves like the deposit
+// contract before anything is sent: it must have code, matching wantCodeHash
+// unless that is zero, and answer get_deposit_count() with an 8 byte count.
+// It returns the keccak256 of the code and the current count.
+func checkDepositContract(ctx context.Context, client *ethclient.Client, address common.Address, wantCodeHash common.Hash) (common.Hash, uint64, error) {
+	codeHash, err := codeHashAt(ctx, client, address)
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	if wantCodeHash != (common.Hash{}) && codeHash != wantCodeHash {
+		return codeHash, 0, fmt.Errorf("code hash of %s is %s, expected %s", address.Hex(), codeHash.Hex(), wantCodeHash.Hex())
+	}
+
+	count, err := readDepositCount(ctx, client, address, nil)
+	if err != nil {
+		return codeHash, 0, err
+	}
+	return codeHash, count, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestCheckDepositContract(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	client := node.client(t)
+	address := common.HexToAddress("0x4242424242424242424242424242424242424242")
+	wantHash := crypto.Keccak256Hash(common.FromHex("0x6080"))
+
+	// The views are called with the built-in ABI, whatever abi.json holds
+	codeHash, count, err := checkDepositContract(context.Background(), client, address, common.Hash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codeHash != wantHash || count != 5 {
+		t.Errorf("code hash %s and count %d, want %s and 5", codeHash.Hex(), count, wantHash.Hex())
+	}
+	if _, _, err := checkDepositContract(context.Background(), client, address, wantHash); err != nil {
+		t.Errorf("matching code hash: %v", err)
+	}
+	if _, _, err := checkDepositContract(context.Background(), client, address, common.HexToHash("0x01")); err == nil || !strings.Contains(err.Error(), "expected 0x0000") {
+		t.Errorf("error %v for another code hash", err)
+	}
+
+	root, err := readDepositRoot(context.Background(), client, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != common.HexToHash(strings.Repeat("ab", 32)) {
+		t.Errorf("deposit root %s", root.Hex())
+	}
+}
+
+func TestDepositCheckAddress(t *testing.T) {
+	holesky, _ := networkByChainID(big.NewInt(holeskyChainID))
+	target := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	tests := []struct {
+		name      string
+		method    string
+		known     bool
+		want      common.Address
+		checkable bool
+	}{
+		{name: "deposit contract", method: defaultDepositMethod, known: true, want: target, checkable: true},
+		{name: "custom contract on an unknown network", method: defaultDepositMethod, want: target, checkable: true},
+		{name: "wrapper", method: "depositFor", known: true, want: holesky.DepositContract, checkable: true},
+		{name: "wrapper on an unknown network", method: "depositFor", want: holesky.DepositContract},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.ContractAddress, cfg.DepositMethod = target.Hex(), tt.method
+			got, checkable := depositCheckAddress(cfg, holesky, tt.known)
+			if checkable != tt.checkable || (checkable && got != tt.want) {
+				t.Errorf("checks %s (%v), want %s (%v)", got.Hex(), checkable, tt.want.Hex(), tt.checkable)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// crossProvider is one of the --cross-verify endpoints.
+type crossProvider struct {
+	// name is the host of the endpoint, whose URL often holds an API key.
+	name   string
+	client *ethclient.Client
+}
+
+// crossVerifier sends every transaction to the --cross-verify providers as
+// well and checks the receipt of each mined one against them, to catch
+// providers that drop transactions or report receipts the others do not
+// have. Disagreements are warnings, the primary RPC_URL stays authoritative.
+type crossVerifier struct {
+	providers []crossProvider
+	// checked holds the transactions whose receipts were compared, a batch
+	// finishes once for each of its deposits.
+	checked map[common.Hash]bool
+}
+
+// endpointName returns the host of an RPC URL, or a placeholder if it does
+// not parse, so that the URL itself is never printed.
+func endpointName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "(unparsable URL)"
+	}
+	return u.Host
+}
+
+// withoutURL drops the request URL an HTTP error carries, which would print
+// the API key of the endpoint.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// newCrossVerifier dials the providers of cfg.CrossVerify, each of which has
+// to be on chainID.
+func newCrossVerifier(ctx context.Context, cfg Config, chainID *big.Int) (*crossVerifier, error) {
+	v := &crossVerifier{checked: make(map[common.Hash]bool)}
+	for _, endpoint := range cfg.CrossVerify {
+		name := endpointName(endpoint)
+		providerCfg := cfg
+		providerCfg.RPCURL = endpoint
+		client, err := dialClient(providerCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", name, err)
+		}
+		id, err := client.ChainID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the chain ID of %s: %w", name, withoutURL(err))
+		}
+		if id.Cmp(chainID) != 0 {
+			return nil, fmt.Errorf("%s is on chain %s, RPC_URL on chain %s", name, id, chainID)
+		}
+		v.providers = append(v.providers, crossProvider{name: name, client: client})
+	}
+	return v, nil
+}
+
+// Broadcast sends tx to every provider and warns about those that do not
+// accept it. Sending a transaction the node already has is harmless, it
+// keeps its nonce and hash.
+func (v *crossVerifier) Broadcast(ctx context.Context, tx *types.Transaction) {
+	if v == nil {
+		return
+	}
+	accepted := 0
+	for _, p := range v.providers {
+		if err := p.client.SendTransaction(ctx, tx); err != nil && !isAlreadyKnown(err) {
+			log.Printf("Warning: cross-verify: %s did not accept %s: %v", p.name, tx.Hash().Hex(), withoutURL(err))
+			continue
+		}
+		accepted++
+	}
+	fmt.Printf("Cross-verify: %s accepted by %d of %d providers\n", tx.Hash().Hex(), accepted, len(v.providers))
+}
+
+// CheckReceipt compares receipt with the one of every provider: missing
+// receipts point at a lagging provider, another block or status at one that
+// is on another fork or lies.
+func (v *crossVerifier) CheckReceipt(ctx context.Context, receipt *types.Receipt) {
+	if v == nil || v.checked[receipt.TxHash] {
+		return
+	}
+	v.checked[receipt.TxHash] = true
+	agreed := 0
+	for _, p := range v.providers {
+		other, err := p.client.TransactionReceipt(ctx, receipt.TxHash)
+		switch {
+		case errors.Is(err, ethereum.NotFound):
+			log.Printf("Warning: cross-verify: %s has no receipt for %s, mined in block %d according to RPC_URL", p.name, receipt.TxHash.Hex(), receipt.BlockNumber)
+		case err != nil:
+			log.Printf("Warning: cross-verify: failed to get the receipt of %s from %s: %v", receipt.TxHash.Hex(), p.name, withoutURL(err))
+		case other.BlockHash != receipt.BlockHash:
+			log.Printf("Warning: cross-verify: %s has %s in block %d (%s), RPC_URL in block %d (%s)",
+				p.name, receipt.TxHash.Hex(), other.BlockNumber, other.BlockHash.Hex(), receipt.BlockNumber, receipt.BlockHash.Hex())
+		case other.Status != receipt.Status:
+			log.Printf("Warning: cross-verify: %s reports status %d for %s, RPC_URL status %d", p.name, other.Status, receipt.TxHash.Hex(), receipt.Status)
+		default:
+			agreed++
+		}
+	}
+	fmt.Printf("Cross-verify: receipt of %s confirmed by %d of %d providers\n", receipt.TxHash.Hex(), agreed, len(v.providers))
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// errExpired marks a transaction that was not (re)broadcast because the
+// chain passed --valid-until-block.
+var errExpired = errors.New("expired")
+
+// checkDeadline returns an errExpired error once the next block would be
+// past validUntil, so that no transaction is published that could only land
+// after it. A validUntil of 0 is no deadline.
+func checkDeadline(ctx context.Context, client *ethclient.Client, validUntil uint64) error {
+	if validUntil == 0 {
+		return nil
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the latest block for --valid-until-block: %w", err)
+	}
+	if head >= validUntil {
+		return fmt.Errorf("%w: the chain is at block %d, --valid-until-block is %d", errExpired, head, validUntil)
+	}
+	return nil
+}
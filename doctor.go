@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// doctorReport prints the checklist of the doctor subcommand as it goes.
+type doctorReport struct {
+	passed, failed, skipped int
+}
+
+func (r *doctorReport) pass(check, format string, args ...any) {
+	r.passed++
+	fmt.Printf("[PASS] %s: %s\n", check, fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) fail(check string, err error) {
+	r.failed++
+	fmt.Printf("[FAIL] %s: %v\n", check, err)
+}
+
+func (r *doctorReport) skip(check, reason string) {
+	r.skipped++
+	fmt.Printf("[SKIP] %s: %s\n", check, reason)
+}
+
+// doctorSample is the deposit the gas estimate is made with: the first entry
+// of the deposit file, or else a placeholder minimum deposit with a valid
+// deposit_data_root, which the deposit contract accepts like any other.
+func doctorSample(path string, cfg Config) (DepositData, string, error) {
+	if path == "" {
+		data := templateEntry()
+		data.Amount = *new(big.Int).Set(minDepositGwei)
+		root, err := computeDepositDataRoot(data.PubKey, data.WithdrawalCredentials, data.Amount.Uint64(), data.Signature)
+		if err != nil {
+			return DepositData{}, "", err
+		}
+		data.DepositDataRoot = root[:]
+		return data, "a placeholder deposit of " + formatGweiAsETH(minDepositGwei) + " ETH", nil
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return DepositData{}, "", err
+	}
+	fieldMap, err := parseFieldMap(cfg.FieldMap)
+	if err != nil {
+		return DepositData{}, "", err
+	}
+	deposits, err := decodeDeposits(file, fieldMap)
+	if err != nil {
+		return DepositData{}, "", err
+	}
+	if len(deposits) == 0 {
+		return DepositData{}, "", fmt.Errorf("%s has no entries", path)
+	}
+	return deposits[0], "entry 0 of " + path, nil
+}
+
+// runDoctor checks the setup of a run end to end without sending anything:
+// the signing key, the node, the network, the ABI and the deposit contract,
+// a gas estimate of a sample deposit and the balance of the account. Checks
+// that depend on a failed one are skipped. It returns the number of failed
+// checks.
+func runDoctor(cfg Config, path string) int {
+	ctx := context.Background()
+	r := &doctorReport{}
+
+	var from common.Address
+	var signer TxSigner
+	if err := cfg.validateSigner(); err != nil {
+		r.fail("Signing key", err)
+	} else if s, err := newTxSigner(cfg); err != nil {
+		r.fail("Signing key", err)
+	} else {
+		signer = s
+		defer signer.Close()
+		from = signer.Address()
+		r.pass("Signing key", "account %s", from.Hex())
+	}
+
+	var contractABI abi.ABI
+	abiLoaded := false
+	if abiFile, err := os.ReadFile("abi.json"); err != nil {
+		if cfg.AbiFromExplorer {
+			r.skip("ABI", "no abi.json, the ABI is fetched with --abi-from-explorer")
+		} else {
+			r.fail("ABI", fmt.Errorf("failed to read abi.json: %w", err))
+		}
+	} else if contractABI, err = abi.JSON(strings.NewReader(string(abiFile))); err != nil {
+		r.fail("ABI", fmt.Errorf("failed to parse abi.json: %w", err))
+	} else {
+		abiLoaded = true
+		r.pass("ABI", "abi.json has %d methods", len(contractABI.Methods))
+	}
+
+	var client *ethclient.Client
+	if cfg.RPCURL == "" {
+		r.fail("RPC", errors.New("RPC_URL is not set"))
+	} else if c, err := dialClient(cfg); err != nil {
+		r.fail("RPC", err)
+	} else if head, err := c.BlockNumber(ctx); err != nil {
+		r.fail("RPC", fmt.Errorf("failed to get the latest block: %w", withoutURL(err)))
+	} else {
+		client = c
+		r.pass("RPC", "%s answers, latest block %d", endpointName(cfg.RPCURL), head)
+	}
+	if client == nil {
+		for _, check := range []string{"Chain ID", "Network", "Deposit contract", "Gas estimate", "Balance"} {
+			r.skip(check, "no connection to the node")
+		}
+		return r.summary()
+	}
+
+	chainID, err := resolveChainID(ctx, client, cfg.ChainID)
+	if err != nil {
+		r.fail("Chain ID", err)
+		for _, check := range []string{"Network", "Deposit contract", "Gas estimate"} {
+			r.skip(check, "unknown chain ID")
+		}
+	} else {
+		r.pass("Chain ID", "%d", chainID)
+
+		n, knownNetwork := networkByChainID(chainID)
+		customContract := cfg.ResolveContract(n, knownNetwork)
+		switch {
+		case knownNetwork:
+			r.pass("Network", "%s", n.Name)
+		case customContract:
+			r.pass("Network", "unknown chain, deposit contract set with --contract")
+		default:
+			r.fail("Network", fmt.Errorf("chain ID %s is not a known network, set the deposit contract with --contract", chainID))
+		}
+
+		depositAddress := cfg.DepositAddress()
+		if cfg.AbiFromExplorer {
+			if fetched, _, err := explorerABI(ctx, cfg.ExplorerAPIURL, cfg.ExplorerAPIKey, chainID, depositAddress); err != nil {
+				r.fail("Explorer ABI", err)
+			} else {
+				contractABI, abiLoaded = mergeABI(contractABI, fetched), true
+				r.pass("Explorer ABI", "verified ABI of %s", depositAddress.Hex())
+			}
+		}
+
+		// A --deposit-method wrapper is only checked for code, the deposit
+		// contract behind it for the rest
+		checkAddress, checkable := depositCheckAddress(cfg, n, knownNetwork)
+		contractOK := true
+		if cfg.DepositMethod != defaultDepositMethod {
+			if codeHash, err := codeHashAt(ctx, client, depositAddress); err != nil {
+				r.fail("Wrapper contract", err)
+				contractOK = false
+			} else {
+				r.pass("Wrapper contract", "%s has code %s", depositAddress.Hex(), codeHash.Hex())
+			}
+		}
+		if !checkable {
+			r.skip("Deposit contract", "no deposit contract known for this chain behind the wrapper")
+		} else if codeHash, count, err := checkDepositContract(ctx, client, checkAddress, common.HexToHash(cfg.ContractCodeHash)); err != nil {
+			r.fail("Deposit contract", err)
+			contractOK = false
+		} else {
+			r.pass("Deposit contract", "%s has code %s and %d deposits", checkAddress.Hex(), codeHash.Hex(), count)
+		}
+		switch {
+		case !contractOK:
+			r.skip("Gas estimate", "no deposit contract")
+		case !abiLoaded:
+			r.skip("Gas estimate", "no ABI")
+		default:
+			r.estimateGas(ctx, cfg, path, contractABI, client, signer, chainID, n)
+		}
+	}
+
+	if signer == nil {
+		r.skip("Balance", "no signing key")
+	} else if balance, err := client.BalanceAt(ctx, from, nil); err != nil {
+		r.fail("Balance", fmt.Errorf("failed to get the balance of %s: %w", from.Hex(), err))
+	} else if balance.Sign() == 0 {
+		r.fail("Balance", fmt.Errorf("%s has no ETH", from.Hex()))
+	} else {
+		r.pass("Balance", "%s ETH", formatWeiAsETH(balance))
+	}
+	return r.summary()
+}
+
+// estimateGas estimates the gas of a sample deposit, see doctorSample.
+func (r *doctorReport) estimateGas(ctx context.Context, cfg Config, path string, contractABI abi.ABI, client *ethclient.Client, signer TxSigner, chainID *big.Int, n network) {
+	if signer == nil {
+		r.skip("Gas estimate", "no signing key")
+		return
+	}
+	call, err := newDepositCall(contractABI, cfg.DepositMethod, cfg.DepositArgs)
+	if err != nil {
+		r.fail("Gas estimate", fmt.Errorf("invalid --deposit-method: %w", err))
+		return
+	}
+	sample, source, err := doctorSample(path, cfg)
+	if err != nil {
+		r.fail("Gas estimate", fmt.Errorf("no sample deposit: %w", err))
+		return
+	}
+	submitter := NewSubmitter(cfg, call, client, signer, chainID, cfg.TxType, n.DepositProfile(), nil)
+	gas, err := submitter.EstimateGas(ctx, sample)
+	if err != nil {
+		r.fail("Gas estimate", fmt.Errorf("%s: %w", source, err))
+		return
+	}
+	r.pass("Gas estimate", "%d gas for %s", gas, source)
+}
+
+func (r *doctorReport) summary() int {
+	fmt.Printf("\n%d passed, %d failed, %d skipped\n", r.passed, r.failed, r.skipped)
+	return r.failed
+}
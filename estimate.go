@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// gasEstimateMargin is the percentage added to an estimate for the gas
+// limit, as state changes between estimation and inclusion.
+const gasEstimateMargin = 20
+
+// gasEstimateFailure is an entry whose gas could not be estimated.
+type gasEstimateFailure struct {
+	data DepositData
+	err  error
+}
+
+// EstimateAll estimates the gas of every deposit with at most workers
+// concurrent requests. Submit then uses the estimates, keyed by entry
+// index, as gas limits instead of --gas-limit. Failures are returned in the
+// order of deposits, entries with undecodable deposit data are left to the
+// validation of Submit.
+func (s *Submitter) EstimateAll(ctx context.Context, deposits []DepositData, workers int) []gasEstimateFailure {
+	gas := make([]uint64, len(deposits))
+	errs := make([]error, len(deposits))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	for i, data := range deposits {
+		if err := data.checkFieldLengths(); err != nil {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			gas[i], errs[i] = s.EstimateGas(ctx, data)
+		}()
+	}
+	wg.Wait()
+
+	s.gasEstimates = make(map[int]uint64, len(deposits))
+	var failures []gasEstimateFailure
+	for i, data := range deposits {
+		if gas[i] == 0 && errs[i] == nil {
+			continue
+		}
+		if errs[i] != nil {
+			failures = append(failures, gasEstimateFailure{data: data, err: errs[i]})
+			continue
+		}
+		s.gasEstimates[data.index] = gas[i] + gas[i]*gasEstimateMargin/100
+	}
+	return failures
+}
+
+// gasLimit is the estimated gas limit of data, or --gas-limit without one.
+func (s *Submitter) gasLimit(data DepositData) uint64 {
+	if gas, ok := s.gasEstimates[data.index]; ok {
+		return gas
+	}
+	return s.cfg.GasLimit
+}
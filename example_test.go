@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Example deposits 32 ETH with a Submitter, on a fake Holesky node that mines
+// every transaction it is asked the receipt of.
+func Example() {
+	node, err := startFakeNode(holeskyChainID)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer node.Close()
+	client, err := ethclient.Dial(node.URL)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer client.Close()
+
+	key, _ := parsePrivateKey(testPrivateKey)
+	chainID := big.NewInt(holeskyChainID)
+	n, known := networkByChainID(chainID)
+	cfg := DefaultConfig()
+	cfg.Yes = true
+	cfg.ResolveContract(n, known)
+	call, _ := newDepositCall(node.abi, defaultDepositMethod, nil)
+	submitter := NewSubmitter(cfg, call, client, keySigner{key}, chainID, txTypeDynamic, n.DepositProfile(), nil)
+
+	data := DepositData{
+		PubKey:                make(HexBytes, pubkeyLength),
+		WithdrawalCredentials: make(HexBytes, withdrawalCredentialsLength),
+		Signature:             make(HexBytes, signatureLength),
+	}
+	data.PubKey[0], data.Signature[0] = 0x11, 0x11
+	data.Amount.SetUint64(32_000_000_000)
+	root, _ := computeDepositDataRoot(data.PubKey, data.WithdrawalCredentials, data.Amount.Uint64(), data.Signature)
+	data.DepositDataRoot = root[:]
+
+	// The Submitter reports each step like the CLI does
+	stdout := os.Stdout
+	os.Stdout, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	tx, err := submitter.Submit(data)
+	var receipt *types.Receipt
+	if err == nil {
+		receipt, err = submitter.WaitForReceipt(tx)
+	}
+	os.Stdout = stdout
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("status %d, %s ETH deposited to %s\n", receipt.Status, formatWeiAsETH(tx.Value()), tx.To().Hex())
+	// Output: status 1, 32 ETH deposited to 0x4242424242424242424242424242424242424242
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type explorerLinks struct {
+	txURL     string
+	beaconURL string
+}
+
+func newExplorerLinks(n network, overrideURL string) explorerLinks {
+	links := explorerLinks{txURL: n.ExplorerURL, beaconURL: n.BeaconExplorerURL}
+	if overrideURL != "" {
+		links.txURL = overrideURL
+	}
+	links.txURL = strings.TrimSuffix(links.txURL, "/")
+	links.beaconURL = strings.TrimSuffix(links.beaconURL, "/")
+	return links
+}
+
+func (l explorerLinks) Print(txHash common.Hash, pubkey string) {
+	if l.txURL != "" {
+		fmt.Printf("Explorer: %s/tx/%s\n", l.txURL, txHash.Hex())
+	}
+	if l.beaconURL != "" {
+		fmt.Printf("Beacon explorer: %s/validator/0x%s\n", l.beaconURL, normalizePubkey(pubkey))
+	}
+}
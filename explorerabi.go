@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// defaultExplorerAPIURL serves verified contract ABIs of every chain it
+// indexes, selected with the chainid parameter.
+const defaultExplorerAPIURL = "https://api.etherscan.io/v2/api"
+
+// explorerABIResponse is the reply of the getabi action, result holds the
+// ABI as a JSON string or the error message.
+type explorerABIResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Result  string `json:"result"`
+}
+
+// abiCachePath is where the verified ABI of address on chainID is cached.
+func abiCachePath(chainID *big.Int, address common.Address) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-deposit", "abi", chainID.String(), strings.ToLower(address.Hex())+".json"), nil
+}
+
+// explorerABI returns the verified ABI of address, from the cache or else
+// from the explorer API, and reports whether it was cached.
+func explorerABI(ctx context.Context, apiURL, apiKey string, chainID *big.Int, address common.Address) (abi.ABI, bool, error) {
+	path, err := abiCachePath(chainID, address)
+	if err != nil {
+		return abi.ABI{}, false, err
+	}
+	if cached, err := os.ReadFile(path); err == nil {
+		parsed, err := abi.JSON(strings.NewReader(string(cached)))
+		if err != nil {
+			return abi.ABI{}, false, fmt.Errorf("cached ABI %s: %w", path, err)
+		}
+		return parsed, true, nil
+	}
+
+	raw, err := fetchExplorerABI(ctx, apiURL, apiKey, chainID, address)
+	if err != nil {
+		return abi.ABI{}, false, err
+	}
+	parsed, err := abi.JSON(strings.NewReader(raw))
+	if err != nil {
+		return abi.ABI{}, false, fmt.Errorf("explorer returned an invalid ABI: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return abi.ABI{}, false, err
+	}
+	if err := os.WriteFile(path, []byte(raw), 0o644); err != nil {
+		return abi.ABI{}, false, err
+	}
+	return parsed, false, nil
+}
+
+func fetchExplorerABI(ctx context.Context, apiURL, apiKey string, chainID *big.Int, address common.Address) (string, error) {
+	query := url.Values{}
+	query.Set("chainid", chainID.String())
+	query.Set("module", "contract")
+	query.Set("action", "getabi")
+	query.Set("address", address.Hex())
+	if apiKey != "" {
+		query.Set("apikey", apiKey)
+	}
+	sep := "?"
+	if strings.Contains(apiURL, "?") {
+		sep = "&"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+sep+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("explorer API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var reply explorerABIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return "", fmt.Errorf("decoding explorer API response: %w", err)
+	}
+	if reply.Status != "1" {
+		return "", fmt.Errorf("explorer API: %s: %s", reply.Message, reply.Result)
+	}
+	return reply.Result, nil
+}
+
+// mergeABI adds the methods, events and errors of fetched to base, replacing
+// those of the same name. base keeps the canonical deposit contract entries
+// used for verification, which a wrapper's ABI may lack.
+func mergeABI(base, fetched abi.ABI) abi.ABI {
+	if base.Methods == nil {
+		return fetched
+	}
+	for name, method := range fetched.Methods {
+		base.Methods[name] = method
+	}
+	for name, event := range fetched.Events {
+		base.Events[name] = event
+	}
+	for name, abiErr := range fetched.Errors {
+		base.Errors[name] = abiErr
+	}
+	return base
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// fakeNode is a JSON-RPC server answering the calls of a deposit run like a
+// node with one deposit contract. Sent transactions are mined when their
+// receipt is first asked for, each in its own block.
+type fakeNode struct {
+	*httptest.Server
+
+	chainID uint64
+	// revert mines every transaction with status 0, neverMine never mines,
+	// sendError rejects every eth_sendRawTransaction with this message.
+	revert    bool
+	neverMine bool
+	sendError string
+	// wrapper is a contract without the views of the deposit contract.
+	wrapper common.Address
+	// noFeeHistory fails eth_feeHistory, and with it the gas strategies.
+	noFeeHistory bool
+
+	mu       sync.Mutex
+	abi      abi.ABI
+	nonce    uint64
+	head     uint64
+	deposits uint64
+	pool     map[common.Hash]*types.Transaction
+	mined    []fakeMined
+	calls    map[string]int
+}
+
+type fakeMined struct {
+	tx    *types.Transaction
+	block uint64
+	index uint64
+}
+
+type fakeRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type fakeError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// newFakeNode starts a fake node on chainID with 5 earlier deposits. It is
+// closed when the test ends.
+func newFakeNode(t testing.TB, chainID uint64) *fakeNode {
+	t.Helper()
+	n, err := startFakeNode(chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(n.Close)
+	return n
+}
+
+// startFakeNode starts a fake node on chainID with 5 earlier deposits, to be
+// closed by the caller.
+func startFakeNode(chainID uint64) (*fakeNode, error) {
+	abiFile, err := os.ReadFile("abi.json")
+	if err != nil {
+		return nil, err
+	}
+	contractABI, err := abi.JSON(strings.NewReader(string(abiFile)))
+	if err != nil {
+		return nil, err
+	}
+	n := &fakeNode{
+		chainID:  chainID,
+		abi:      contractABI,
+		head:     100,
+		deposits: 5,
+		pool:     make(map[common.Hash]*types.Transaction),
+		calls:    make(map[string]int),
+	}
+	n.Server = httptest.NewServer(http.HandlerFunc(n.serveHTTP))
+	return n, nil
+}
+
+// submitter returns a Submitter of the test account sending to the fake
+// node's deposit contract without confirmations. cfg is DefaultConfig if nil.
+func (n *fakeNode) submitter(t testing.TB, cfg *Config) *Submitter {
+	t.Helper()
+	c := DefaultConfig()
+	if cfg != nil {
+		c = *cfg
+	}
+	c.RPCURL = n.URL
+	c.Yes = true
+	chainID := new(big.Int).SetUint64(n.chainID)
+	network, known := networkByChainID(chainID)
+	c.ResolveContract(network, known)
+	client := n.client(t)
+	key, err := parsePrivateKey(testPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err := newDepositCall(n.abi, defaultDepositMethod, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSubmitter(c, call, client, keySigner{key}, chainID, txTypeDynamic, network.DepositProfile(), nil)
+}
+
+// client returns a client of the fake node, closed when the test ends.
+func (n *fakeNode) client(t testing.TB) *ethclient.Client {
+	t.Helper()
+	client, err := ethclient.Dial(n.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// Calls returns how often method was called.
+func (n *fakeNode) Calls(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+// Sent returns the transactions sent so far, mined or not.
+func (n *fakeNode) Sent() []*types.Transaction {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var txs []*types.Transaction
+	for _, m := range n.mined {
+		txs = append(txs, m.tx)
+	}
+	for _, tx := range n.pool {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+func (n *fakeNode) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	answer := func(req fakeRequest) map[string]any {
+		out := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		if result, err := n.handle(req.Method, req.Params); err != nil {
+			out["error"] = err
+		} else {
+			out["result"] = result
+		}
+		return out
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if len(raw) > 0 && raw[0] == '[' {
+		var reqs []fakeRequest
+		json.Unmarshal(raw, &reqs)
+		var answers []any
+		for _, req := range reqs {
+			answers = append(answers, answer(req))
+		}
+		json.NewEncoder(w).Encode(answers)
+		return
+	}
+	var req fakeRequest
+	json.Unmarshal(raw, &req)
+	json.NewEncoder(w).Encode(answer(req))
+}
+
+func (n *fakeNode) handle(method string, rawParams []json.RawMessage) (any, *fakeError) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls[method]++
+	var params []any
+	for _, p := range rawParams {
+		var v any
+		json.Unmarshal(p, &v)
+		params = append(params, v)
+	}
+
+	switch method {
+	case "eth_chainId":
+		return hexutil.Uint64(n.chainID), nil
+	case "net_version":
+		return fmt.Sprint(n.chainID), nil
+	case "eth_getTransactionCount":
+		return hexutil.Uint64(n.nonce), nil
+	case "eth_maxPriorityFeePerGas":
+		return hexutil.Uint64(1_000_000_000), nil
+	case "eth_gasPrice":
+		return hexutil.Uint64(2_000_000_000), nil
+	case "eth_getCode":
+		return "0x6080", nil
+	case "eth_estimateGas":
+		return hexutil.Uint64(60000), nil
+	case "eth_getBalance":
+		return (*hexutil.Big)(new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))), nil
+	case "eth_blockNumber":
+		n.head++
+		return hexutil.Uint64(n.head), nil
+	case "eth_getBlockByNumber":
+		return n.block(n.blockParam(params[0])), nil
+	case "eth_feeHistory":
+		if n.noFeeHistory {
+			return nil, &fakeError{Code: -32601, Message: "the method eth_feeHistory does not exist/is not available"}
+		}
+		var rewards [][]string
+		var fees []string
+		var ratios []float64
+		for i := 0; i < 4; i++ {
+			rewards = append(rewards, []string{"0x3b9aca00", "0x77359400", "0xb2d05e00"})
+			fees = append(fees, "0x7")
+			ratios = append(ratios, 0.5)
+		}
+		return map[string]any{"oldestBlock": hexutil.Uint64(n.head - 3), "reward": rewards, "baseFeePerGas": append(fees, "0x7"), "gasUsedRatio": ratios}, nil
+	case "eth_call":
+		call := params[0].(map[string]any)
+		data, _ := call["input"].(string)
+		if data == "" {
+			data, _ = call["data"].(string)
+		}
+		block := n.head
+		if len(params) > 1 {
+			block = n.blockParam(params[1])
+		}
+		switch {
+		case common.HexToAddress(call["to"].(string)) == n.wrapper:
+			return nil, &fakeError{Code: 3, Message: "execution reverted"}
+		case strings.HasPrefix(data, hexutil.Encode(n.abi.Methods["get_deposit_root"].ID)):
+			return "0x" + strings.Repeat("ab", 32), nil
+		case strings.HasPrefix(data, hexutil.Encode(n.abi.Methods["get_deposit_count"].ID)):
+			return hexutil.Bytes(n.packCount(n.countAt(block))), nil
+		}
+		return "0x", nil
+	case "eth_sendRawTransaction":
+		if n.sendError != "" {
+			return nil, &fakeError{Code: -32000, Message: n.sendError}
+		}
+		raw, _ := hexutil.Decode(params[0].(string))
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			return nil, &fakeError{Code: -32000, Message: err.Error()}
+		}
+		if _, ok := n.pool[tx.Hash()]; ok {
+			return nil, &fakeError{Code: -32000, Message: "already known"}
+		}
+		if tx.Nonce() < n.nonce {
+			return nil, &fakeError{Code: -32000, Message: "nonce too low"}
+		}
+		n.nonce = tx.Nonce() + 1
+		n.pool[tx.Hash()] = tx
+		return tx.Hash().Hex(), nil
+	case "eth_getTransactionReceipt":
+		hash := common.HexToHash(params[0].(string))
+		if !n.neverMine {
+			n.mine()
+		}
+		for _, m := range n.mined {
+			if m.tx.Hash() == hash {
+				return n.receipt(m), nil
+			}
+		}
+		return nil, nil
+	case "eth_getTransactionByHash":
+		hash := common.HexToHash(params[0].(string))
+		for _, m := range n.mined {
+			if m.tx.Hash() == hash {
+				return m.tx, nil
+			}
+		}
+		if tx, ok := n.pool[hash]; ok {
+			return tx, nil
+		}
+		return nil, nil
+	case "eth_getLogs":
+		filter := params[0].(map[string]any)
+		addresses := make(map[string]bool)
+		switch a := filter["address"].(type) {
+		case string:
+			addresses[common.HexToAddress(a).Hex()] = true
+		case []any:
+			for _, address := range a {
+				addresses[common.HexToAddress(address.(string)).Hex()] = true
+			}
+		}
+		logs := []any{}
+		for _, m := range n.mined {
+			for _, l := range n.receipt(m)["logs"].([]any) {
+				if len(addresses) == 0 || addresses[l.(map[string]any)["address"].(string)] {
+					logs = append(logs, l)
+				}
+			}
+		}
+		return logs, nil
+	}
+	return nil, &fakeError{Code: -32601, Message: "the method " + method + " does not exist/is not available"}
+}
+
+// mine includes every pooled transaction in a block of its own, in nonce order.
+func (n *fakeNode) mine() {
+	for len(n.pool) > 0 {
+		var next *types.Transaction
+		for _, tx := range n.pool {
+			if next == nil || tx.Nonce() < next.Nonce() {
+				next = tx
+			}
+		}
+		delete(n.pool, next.Hash())
+		n.head++
+		n.mined = append(n.mined, fakeMined{tx: next, block: n.head, index: n.deposits + uint64(len(n.mined))})
+	}
+}
+
+func (n *fakeNode) countAt(block uint64) uint64 {
+	count := n.deposits
+	for _, m := range n.mined {
+		if m.block <= block && !n.revert {
+			count++
+		}
+	}
+	return count
+}
+
+func (n *fakeNode) packCount(count uint64) []byte {
+	le := make([]byte, 8)
+	binary.LittleEndian.PutUint64(le, count)
+	out, _ := n.abi.Methods["get_deposit_count"].Outputs.Pack(le)
+	return out
+}
+
+func (n *fakeNode) blockParam(p any) uint64 {
+	s, _ := p.(string)
+	switch s {
+	case "", "latest", "pending", "safe", "finalized":
+		return n.head
+	}
+	v, _ := hexutil.DecodeUint64(s)
+	return v
+}
+
+func (n *fakeNode) block(number uint64) map[string]any {
+	zero := common.Hash{}.Hex()
+	b := map[string]any{
+		"number":           hexutil.Uint64(number),
+		"parentHash":       common.BigToHash(new(big.Int).SetUint64(number - 1)).Hex(),
+		"timestamp":        hexutil.Uint64(1_700_000_000 + number*12),
+		"gasLimit":         hexutil.Uint64(30_000_000),
+		"gasUsed":          hexutil.Uint64(15_000_000),
+		"baseFeePerGas":    hexutil.Uint64(7),
+		"miner":            common.Address{}.Hex(),
+		"difficulty":       "0x0",
+		"extraData":        "0x",
+		"logsBloom":        hexutil.Bytes(make([]byte, 256)),
+		"nonce":            "0x0000000000000000",
+		"mixHash":          zero,
+		"receiptsRoot":     zero,
+		"stateRoot":        zero,
+		"sha3Uncles":       types.EmptyUncleHash.Hex(),
+		"transactionsRoot": types.EmptyTxsHash.Hex(),
+		"transactions":     []any{},
+		"uncles":           []any{},
+		"size":             "0x100",
+	}
+	raw, _ := json.Marshal(b)
+	var header types.Header
+	json.Unmarshal(raw, &header)
+	b["hash"] = header.Hash().Hex()
+	return b
+}
+
+func (n *fakeNode) blockHash(number uint64) string {
+	return n.block(number)["hash"].(string)
+}
+
+// receipt is the receipt of m with the DepositEvent the deposit contract
+// emits, or none if the transaction reverted. A call of the wrapper takes the
+// parameters of deposit and is forwarded to the deposit contract of the
+// chain, which emits the event.
+func (n *fakeNode) receipt(m fakeMined) map[string]any {
+	logs := []any{}
+	status := "0x1"
+	method, err := n.abi.MethodById(m.tx.Data())
+	emitter := *m.tx.To()
+	if emitter == n.wrapper && len(m.tx.Data()) >= 4 {
+		deposit := n.abi.Methods["deposit"]
+		method, err = &deposit, nil
+		emitter = networks[n.chainID].DepositContract
+	}
+	if n.revert {
+		status = "0x0"
+	} else if err == nil && method.Name == "deposit" {
+		args, err := method.Inputs.Unpack(m.tx.Data()[4:])
+		if err == nil {
+			amount := make([]byte, 8)
+			binary.LittleEndian.PutUint64(amount, new(big.Int).Div(m.tx.Value(), big.NewInt(1e9)).Uint64())
+			index := make([]byte, 8)
+			binary.LittleEndian.PutUint64(index, m.index)
+			event := n.abi.Events["DepositEvent"]
+			packed, _ := event.Inputs.Pack(args[0], args[1], amount, args[2], index)
+			logs = append(logs, map[string]any{
+				"address":          emitter.Hex(),
+				"topics":           []string{event.ID.Hex()},
+				"data":             hexutil.Bytes(packed),
+				"blockNumber":      hexutil.Uint64(m.block),
+				"blockHash":        n.blockHash(m.block),
+				"transactionHash":  m.tx.Hash().Hex(),
+				"transactionIndex": "0x0",
+				"logIndex":         "0x0",
+				"removed":          false,
+			})
+		}
+	}
+	return map[string]any{
+		"transactionHash":   m.tx.Hash().Hex(),
+		"transactionIndex":  "0x0",
+		"blockHash":         n.blockHash(m.block),
+		"blockNumber":       hexutil.Uint64(m.block),
+		"from":              common.Address{}.Hex(),
+		"to":                m.tx.To().Hex(),
+		"cumulativeGasUsed": hexutil.Uint64(60000),
+		"gasUsed":           hexutil.Uint64(60000),
+		"effectiveGasPrice": hexutil.Uint64(1_000_000_007),
+		"contractAddress":   nil,
+		"logs":              logs,
+		"logsBloom":         hexutil.Bytes(make([]byte, 256)),
+		"status":            status,
+		"type":              hexutil.Uint64(m.tx.Type()),
+	}
+}
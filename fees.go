@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// parseGwei parses an amount in (possibly fractional) gwei into wei.
+func parseGwei(s string) (*big.Int, error) {
+	gwei, ok := new(big.Rat).SetString(s)
+	if !ok || gwei.Sign() < 0 {
+		return nil, fmt.Errorf("invalid gwei amount %q", s)
+	}
+	wei := gwei.Mul(gwei, new(big.Rat).SetInt64(1e9))
+	if !wei.IsInt() {
+		return nil, fmt.Errorf("gwei amount %q has more than 9 decimals", s)
+	}
+	return new(big.Int).Set(wei.Num()), nil
+}
+
+// Fees returns the optional per-entry gas tip and fee caps in wei, nil when
+// the entry does not set them.
+func (d DepositData) Fees() (tipCap, feeCap *big.Int, err error) {
+	if d.GasTipCapGwei != "" {
+		if tipCap, err = parseGwei(d.GasTipCapGwei.String()); err != nil {
+			return nil, nil, fmt.Errorf("gas_tip_cap_gwei: %w", err)
+		}
+	}
+	if d.GasFeeCapGwei != "" {
+		if feeCap, err = parseGwei(d.GasFeeCapGwei.String()); err != nil {
+			return nil, nil, fmt.Errorf("gas_fee_cap_gwei: %w", err)
+		}
+	}
+	return tipCap, feeCap, nil
+}
+
+// depositFees returns the gas tip and fee caps for a transaction carrying
+// deposits: the highest per-entry caps, falling back to the configured ones.
+// Either may be nil, meaning the node's suggestion.
+func depositFees(cfg Config, deposits []DepositData) (tipCap, feeCap *big.Int, err error) {
+	for _, data := range deposits {
+		tip, fee, err := data.Fees()
+		if err != nil {
+			return nil, nil, fmt.Errorf("deposit %d: %w", data.index, err)
+		}
+		if tip != nil && (tipCap == nil || tip.Cmp(tipCap) > 0) {
+			tipCap = tip
+		}
+		if fee != nil && (feeCap == nil || fee.Cmp(feeCap) > 0) {
+			feeCap = fee
+		}
+	}
+	if tipCap == nil {
+		tipCap = cfg.GasTipCap
+	}
+	if feeCap == nil {
+		feeCap = cfg.GasFeeCap
+	}
+	return tipCap, feeCap, nil
+}
+
+// checkEntryFees validates the per-entry fee caps against each other and the
+// configured caps they are combined with.
+func checkEntryFees(cfg Config, deposits []DepositData) error {
+	for _, data := range deposits {
+		tipCap, feeCap, err := depositFees(cfg, []DepositData{data})
+		if err != nil {
+			return err
+		}
+		if tipCap != nil && feeCap != nil && tipCap.Cmp(feeCap) > 0 {
+			return fmt.Errorf("deposit %d: gas tip cap %s wei exceeds gas fee cap %s wei", data.index, tipCap, feeCap)
+		}
+	}
+	return nil
+}
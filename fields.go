@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// depositFields are the JSON names of the DepositData fields.
+var depositFields = []string{"amount", "pubkey", "withdrawal_credentials", "signature", "deposit_data_root", "deposit_message_root", "fork_version", "genesis_validators_root", "network_name", "gas_fee_cap_gwei", "gas_tip_cap_gwei"}
+
+// fieldAliases maps normalized spellings used by other key generators to the
+// DepositData field names. Keys are normalized with normalizeFieldName.
+var fieldAliases = map[string]string{
+	"amount":                "amount",
+	"pubkey":                "pubkey",
+	"publickey":             "pubkey",
+	"validatorpubkey":       "pubkey",
+	"withdrawalcredentials": "withdrawal_credentials",
+	"withdrawalcreds":       "withdrawal_credentials",
+	"signature":             "signature",
+	"sig":                   "signature",
+	"depositdataroot":       "deposit_data_root",
+	"dataroot":              "deposit_data_root",
+	"depositmessageroot":    "deposit_message_root",
+	"messageroot":           "deposit_message_root",
+	"forkversion":           "fork_version",
+	"genesisvalidatorsroot": "genesis_validators_root",
+	"networkname":           "network_name",
+	"gasfeecapgwei":         "gas_fee_cap_gwei",
+	"gastipcapgwei":         "gas_tip_cap_gwei",
+}
+
+// normalizeFieldName lowercases name and drops '_' and '-', so that pubKey,
+// pub_key and PUBKEY compare equal.
+func normalizeFieldName(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// parseFieldMap parses a --field-map value of the form "from=to,from=to"
+// where every "to" is a DepositData field name.
+func parseFieldMap(value string) (map[string]string, error) {
+	fieldMap := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" {
+			return nil, fmt.Errorf("invalid field mapping %q, expected from=to", pair)
+		}
+		if !isDepositField(to) {
+			return nil, fmt.Errorf("invalid field mapping %q, %q is not one of %s", pair, to, strings.Join(depositFields, ", "))
+		}
+		fieldMap[normalizeFieldName(from)] = to
+	}
+	return fieldMap, nil
+}
+
+func isDepositField(name string) bool {
+	for _, field := range depositFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeDeposits unmarshals a deposit data file, accepting the aliases of
+// fieldAliases and the renames of fieldMap, which take precedence. Unknown
+// fields are ignored as before.
+//
+// Files of staking-deposit-cli, Wagyu Key Gen and ethdo are all accepted:
+// ethdo writes its hex fields with a 0x prefix, which is dropped, and a
+// file holding a single deposit object instead of an array is read as one
+// entry. An amount may also be a string with a unit, see parseAmount.
+func decodeDeposits(file []byte, fieldMap map[string]string) ([]DepositData, error) {
+	var entries []map[string]json.RawMessage
+	if trimmed := bytes.TrimSpace(file); len(trimmed) > 0 && trimmed[0] == '{' {
+		var entry map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	} else if err := json.Unmarshal(file, &entries); err != nil {
+		return nil, err
+	}
+
+	deposits := make([]DepositData, len(entries))
+	for i, entry := range entries {
+		canonical := make(map[string]json.RawMessage, len(entry))
+		source := make(map[string]string, len(entry))
+		for name, value := range entry {
+			field, ok := fieldMap[normalizeFieldName(name)]
+			if !ok {
+				field, ok = fieldAliases[normalizeFieldName(name)]
+			}
+			if !ok {
+				continue
+			}
+			if other, dup := source[field]; dup {
+				return nil, fmt.Errorf("entry %d: fields %q and %q both map to %q", i, other, name, field)
+			}
+			source[field] = name
+			canonical[field] = value
+		}
+
+		if amount, ok := canonical["amount"]; ok && bytes.HasPrefix(bytes.TrimSpace(amount), []byte{'"'}) {
+			var s string
+			if err := json.Unmarshal(amount, &s); err != nil {
+				return nil, fmt.Errorf("entry %d: %w", i, err)
+			}
+			gwei, err := parseAmount(s)
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: %w", i, err)
+			}
+			canonical["amount"] = json.RawMessage(gwei.String())
+		}
+
+		raw, err := json.Marshal(canonical)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if err := json.Unmarshal(raw, &deposits[i]); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		deposits[i].normalizeHex()
+		deposits[i].index = i
+	}
+	return deposits, nil
+}
+
+// normalizeHex drops surrounding whitespace and the 0x prefix from the hex
+// fields kept as strings, which are stored without it as written by
+// staking-deposit-cli. The HexBytes fields are decoded either way.
+func (d *DepositData) normalizeHex() {
+	for _, field := range []*string{&d.ForkVersion, &d.GenesisValidatorsRoot} {
+		*field = trimHex(*field)
+	}
+}
+
+// trimHex drops surrounding whitespace and an optional 0x or 0X prefix.
+func trimHex(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return s[2:]
+	}
+	return s
+}
+
+// decodeHex decodes a hex field with or without the 0x prefix.
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(trimHex(s))
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// TestDecodeDepositsGenerators reads a deposit in the file layouts of the key
+// generators the README lists.
+func TestDecodeDepositsGenerators(t *testing.T) {
+	entry := testEntry(t, 0x11, 32_000_000_000)
+	hexField := func(name string) string { return entry[name].(string) }
+
+	tests := []struct {
+		name string
+		file any
+	}{
+		{name: "staking-deposit-cli", file: []map[string]any{{
+			"pubkey":                  hexField("pubkey"),
+			"withdrawal_credentials":  hexField("withdrawal_credentials"),
+			"amount":                  32_000_000_000,
+			"signature":               hexField("signature"),
+			"deposit_message_root":    strings.Repeat("ab", 32),
+			"deposit_data_root":       hexField("deposit_data_root"),
+			"fork_version":            "01017000",
+			"network_name":            "holesky",
+			"deposit_cli_version":     "2.7.0",
+			"genesis_validators_root": strings.Repeat("cd", 32),
+		}}},
+		// Wagyu Key Gen runs staking-deposit-cli and writes its layout,
+		// keeping the fields in a different order.
+		{name: "wagyu key gen", file: []map[string]any{{
+			"amount":                 32_000_000_000,
+			"deposit_data_root":      hexField("deposit_data_root"),
+			"signature":              hexField("signature"),
+			"withdrawal_credentials": hexField("withdrawal_credentials"),
+			"pubkey":                 hexField("pubkey"),
+			"fork_version":           "01017000",
+			"network_name":           "holesky",
+		}}},
+		// ethdo validator depositdata writes a single object for a single
+		// validator, with 0x prefixed hex fields.
+		{name: "ethdo", file: map[string]any{
+			"name":                   "Deposit for interop/00000",
+			"account":                "interop/00000",
+			"pubkey":                 "0x" + hexField("pubkey"),
+			"withdrawal_credentials": "0x" + hexField("withdrawal_credentials"),
+			"signature":              "0x" + hexField("signature"),
+			"amount":                 32_000_000_000,
+			"deposit_data_root":      "0x" + hexField("deposit_data_root"),
+			"deposit_message_root":   "0x" + strings.Repeat("ab", 32),
+			"fork_version":           "0x01017000",
+			"version":                3,
+		}},
+		{name: "other spellings", file: []map[string]any{{
+			"publicKey":       "0x" + hexField("pubkey"),
+			"withdrawalCreds": hexField("withdrawal_credentials"),
+			"sig":             hexField("signature"),
+			"amount":          "32 ETH",
+			"dataRoot":        hexField("deposit_data_root"),
+			"Fork-Version":    "0x01017000",
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			deposits, err := decodeDeposits(raw, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(deposits) != 1 {
+				t.Fatalf("%d entries, want 1", len(deposits))
+			}
+			data := deposits[0]
+			if data.PubKey.String() != hexField("pubkey") || data.WithdrawalCredentials.String() != hexField("withdrawal_credentials") ||
+				data.Signature.String() != hexField("signature") || data.DepositDataRoot.String() != hexField("deposit_data_root") {
+				t.Errorf("decoded %s, %s, %s, %s, want the fields of the entry", data.PubKey, data.WithdrawalCredentials, data.Signature, data.DepositDataRoot)
+			}
+			if data.Amount.Uint64() != 32_000_000_000 {
+				t.Errorf("amount %s, want 32000000000", &data.Amount)
+			}
+			if data.ForkVersion != "01017000" {
+				t.Errorf("fork version %q, want 01017000", data.ForkVersion)
+			}
+			if data.index != 0 {
+				t.Errorf("index %d, want 0", data.index)
+			}
+			if err := checkDepositDataRoot(data); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestDecodeDepositsPaddedHex decodes hex fields with a 0x or 0X prefix and
+// surrounding whitespace, in a file with a single deposit object.
+func TestDecodeDepositsPaddedHex(t *testing.T) {
+	holesky, _ := networkByChainID(big.NewInt(holeskyChainID))
+	entry := testEntry(t, 0x11, 32_000_000_000)
+	want := make(map[string]string)
+	for _, field := range []string{"pubkey", "withdrawal_credentials", "signature", "deposit_data_root"} {
+		want[field] = entry[field].(string)
+		entry[field] = " 0X" + strings.ToUpper(want[field]) + "\n"
+	}
+	entry["fork_version"] = "\t0x01017000 "
+	entry["genesis_validators_root"] = " 0X" + strings.ToUpper(hex.EncodeToString(holesky.GenesisValidatorsRoot[:]))
+	raw, _ := json.Marshal(entry)
+
+	deposits, err := decodeDeposits(raw, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deposits) != 1 {
+		t.Fatalf("%d entries, want 1", len(deposits))
+	}
+	data := deposits[0]
+	got := map[string]string{
+		"pubkey":                 data.PubKey.String(),
+		"withdrawal_credentials": data.WithdrawalCredentials.String(),
+		"signature":              data.Signature.String(),
+		"deposit_data_root":      data.DepositDataRoot.String(),
+	}
+	for field, value := range want {
+		if got[field] != value {
+			t.Errorf("%s decoded to %s, want %s", field, got[field], value)
+		}
+	}
+	if data.ForkVersion != "01017000" {
+		t.Errorf("fork version %q, want 01017000", data.ForkVersion)
+	}
+	if mismatched := genesisRootMismatches(deposits, holesky); len(mismatched) != 0 {
+		t.Errorf("genesis root %q does not match holesky", data.GenesisValidatorsRoot)
+	}
+	if version, err := parseForkVersion(data.ForkVersion); err != nil || version != holesky.GenesisForkVersion {
+		t.Errorf("fork version %x (%v), want %x", version, err, holesky.GenesisForkVersion)
+	}
+	if err := checkDepositDataRoot(data); err != nil {
+		t.Error(err)
+	}
+
+	for _, s := range []string{"0xab", "0XAB", " ab\n"} {
+		if b, err := decodeHex(s); err != nil || len(b) != 1 || b[0] != 0xab {
+			t.Errorf("decodeHex(%q) = %x, %v", s, b, err)
+		}
+	}
+	if _, err := decodeHex("0x0xab"); err == nil {
+		t.Error("decodeHex accepted a doubled prefix")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+func normalizePubkey(pubkey string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(pubkey), "0x"))
+}
+
+// parsePubkeyFilter accepts either a comma separated list of pubkeys or the
+// path to a file listing pubkeys, one per line or comma separated.
+func parsePubkeyFilter(value string) (map[string]bool, error) {
+	if info, err := os.Stat(value); err == nil && !info.IsDir() {
+		contents, err := os.ReadFile(value)
+		if err != nil {
+			return nil, err
+		}
+		value = string(contents)
+	}
+
+	wanted := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	}) {
+		if pubkey := normalizePubkey(field); pubkey != "" {
+			wanted[pubkey] = true
+		}
+	}
+	return wanted, nil
+}
+
+// filterByPubkey keeps the deposits whose pubkey is wanted and returns the
+// wanted pubkeys that no deposit matched.
+func filterByPubkey(deposits []DepositData, wanted map[string]bool) ([]DepositData, []string) {
+	found := make(map[string]bool)
+	var kept []DepositData
+	for _, data := range deposits {
+		pubkey := data.PubKey.String()
+		if wanted[pubkey] {
+			found[pubkey] = true
+			kept = append(kept, data)
+		}
+	}
+
+	var missing []string
+	for pubkey := range wanted {
+		if !found[pubkey] {
+			missing = append(missing, pubkey)
+		}
+	}
+	sort.Strings(missing)
+	return kept, missing
+}
+
+// duplicateDeposit is an entry repeating the pubkey and amount of an earlier
+// one. Index and FirstIndex are indices in the deposit file.
+type duplicateDeposit struct {
+	Index      int
+	FirstIndex int
+	PubKey     string
+}
+
+// dedupeDeposits returns deposits without the entries that repeat the pubkey
+// and amount of an earlier entry, together with those duplicates.
+func dedupeDeposits(deposits []DepositData) ([]DepositData, []duplicateDeposit) {
+	first := make(map[string]int)
+	var kept []DepositData
+	var duplicates []duplicateDeposit
+	for _, data := range deposits {
+		pubkey := data.PubKey.String()
+		key := pubkey + "/" + data.Amount.String()
+		if j, ok := first[key]; ok {
+			duplicates = append(duplicates, duplicateDeposit{Index: data.index, FirstIndex: j, PubKey: pubkey})
+			continue
+		}
+		first[key] = data.index
+		kept = append(kept, data)
+	}
+	return kept, duplicates
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDuplicateIndices reports duplicates by their index in the deposit file,
+// also when earlier entries were left out, as --resubmit-failed does.
+func TestDuplicateIndices(t *testing.T) {
+	deposits := []DepositData{testDepositData(t, 0x11, 32_000_000_000), testDepositData(t, 0x22, 32_000_000_000), testDepositData(t, 0x11, 32_000_000_000)}
+	for i, index := range []int{2, 5, 7} {
+		deposits[i].index = index
+	}
+
+	kept, duplicates := dedupeDeposits(deposits)
+	if len(kept) != 2 || len(duplicates) != 1 {
+		t.Fatalf("kept %d and %d duplicates, want 2 and 1", len(kept), len(duplicates))
+	}
+	if d := duplicates[0]; d.Index != 7 || d.FirstIndex != 2 {
+		t.Errorf("entry %d duplicates entry %d, want 7 and 2", d.Index, d.FirstIndex)
+	}
+
+	problems := ValidateBatch(deposits)
+	if len(problems) != 1 || problems[0].Index != 7 || problems[0].Err.Error() != "repeats the pubkey and amount of entry 2" {
+		t.Errorf("problems %v, want entry 7 repeating entry 2", problems)
+	}
+
+	deposits[1].ForkVersion = "zz"
+	holesky, _ := networkByChainID(big.NewInt(holeskyChainID))
+	err := dumpSigningData(filepath.Join(t.TempDir(), "signing.json"), deposits, holesky, true)
+	if err == nil || !strings.HasPrefix(err.Error(), "deposit 5: ") {
+		t.Errorf("error %v, want one of deposit 5", err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// waitConfirmations waits until every mined receipt of results has at least
+// blocks confirmations, its own block counting as the first, and reports the
+// progress of the youngest one, polling the head with poll. The receipts are
+// then checked for reorgs once more; if one moved, its new block has to get
+// as deep as well.
+func waitConfirmations(ctx context.Context, client *ethclient.Client, results []depositReceipt, blocks, validUntil uint64, poll pollPolicy) error {
+	for {
+		var youngest uint64
+		for _, r := range results {
+			if r.err == nil {
+				youngest = max(youngest, r.receipt.BlockNumber.Uint64())
+			}
+		}
+		if youngest == 0 {
+			return nil
+		}
+
+		reported := uint64(0)
+		var interval time.Duration
+		for {
+			head, err := client.BlockNumber(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get the latest block: %w", err)
+			}
+			confirmations := uint64(0)
+			if head >= youngest {
+				confirmations = head - youngest + 1
+			}
+			if confirmations >= blocks {
+				break
+			}
+			if confirmations != reported {
+				fmt.Printf("Finality: %d of %d confirmations, block %d of %d\n", confirmations, blocks, head, youngest+blocks-1)
+				reported = confirmations
+			}
+			// Never sleep past the block that completes the wait
+			interval = min(poll.next(interval), time.Duration(blocks-confirmations)*slotDuration)
+			if err := sleepContext(ctx, interval); err != nil {
+				return err
+			}
+		}
+
+		if checkReorgs(ctx, client, results, validUntil, poll) == 0 {
+			fmt.Printf("All mined deposits have %d confirmations\n", blocks)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+)
+
+// approveFees shows the fees of the next transaction of deposits with the
+// current base fee and asks the operator to approve them or fetch them
+// again, so that a fee spike can be waited out. It returns the approved
+// fees, or a nil feeCap if the operator cancelled.
+func (s *Submitter) approveFees(deposits []DepositData, tipCap, feeCap *big.Int, gasLimit uint64) (*big.Int, *big.Int, error) {
+	for {
+		if header, err := s.client.HeaderByNumber(context.Background(), nil); err != nil {
+			log.Printf("Warning: failed to get the latest base fee: %v", err)
+		} else if header.BaseFee != nil {
+			fmt.Printf("Base fee: %s gwei (block %d)\n", formatWeiAsGwei(header.BaseFee), header.Number)
+		}
+		if tipCap != nil {
+			fmt.Printf("Fees: tip cap %s gwei, fee cap %s gwei", formatWeiAsGwei(tipCap), formatWeiAsGwei(feeCap))
+		} else {
+			fmt.Printf("Fees: gas price %s gwei", formatWeiAsGwei(feeCap))
+		}
+		maxFee := new(big.Int).Mul(feeCap, new(big.Int).SetUint64(gasLimit))
+		fmt.Printf(", at most %s ETH for %d gas\n", formatWeiAsETH(maxFee), gasLimit)
+		if s.cfg.GasTipCap != nil || s.cfg.GasFeeCap != nil {
+			fmt.Printf("Fees set by --gas-tip-cap or --gas-fee-cap do not change when fetched again\n")
+		}
+
+		fmt.Printf("Approve fees? (y/n, r = fetch again): ")
+		answer, answered := readLineTimeout(s.cfg.ConfirmTimeout)
+		if !answered {
+			fmt.Printf("\nNo answer within %s\n", s.cfg.ConfirmTimeout)
+			return nil, nil, nil
+		}
+		switch answer {
+		case "y":
+			return tipCap, feeCap, nil
+		case "r":
+			var err error
+			if tipCap, feeCap, err = s.fees(deposits, gasLimit); err != nil {
+				return nil, nil, err
+			}
+		default:
+			return nil, nil, nil
+		}
+	}
+}
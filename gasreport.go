@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// minedGas is the gas a mined transaction used and its effective price.
+type minedGas struct {
+	used  uint64
+	price *big.Int
+}
+
+func (g minedGas) fee() *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(g.used), g.price)
+}
+
+// gasReport sums up the gas of the mined transactions of a run for
+// --gas-report. The average gas price is weighted by gas, i.e. the total
+// cost divided by the total gas.
+type gasReport struct {
+	Transactions    int    `json:"transactions"`
+	MinGasUsed      uint64 `json:"min_gas_used"`
+	MaxGasUsed      uint64 `json:"max_gas_used"`
+	AvgGasUsed      uint64 `json:"avg_gas_used"`
+	TotalGasUsed    uint64 `json:"total_gas_used"`
+	MinGasPriceGwei string `json:"min_gas_price_gwei"`
+	MaxGasPriceGwei string `json:"max_gas_price_gwei"`
+	AvgGasPriceGwei string `json:"avg_gas_price_gwei"`
+	TotalCostETH    string `json:"total_cost_eth"`
+}
+
+// reportWithGas is the --summary-file document with --gas-report.
+type reportWithGas struct {
+	Entries   []entryOutcome `json:"entries"`
+	GasReport gasReport      `json:"gas_report"`
+}
+
+// GasReport returns the gas statistics of the transactions mined so far.
+func (r *runSummary) GasReport() gasReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var report gasReport
+	var minPrice, maxPrice *big.Int
+	cost := new(big.Int)
+	for _, gas := range r.mined {
+		if report.Transactions == 0 || gas.used < report.MinGasUsed {
+			report.MinGasUsed = gas.used
+		}
+		report.MaxGasUsed = max(report.MaxGasUsed, gas.used)
+		report.TotalGasUsed += gas.used
+		if minPrice == nil || gas.price.Cmp(minPrice) < 0 {
+			minPrice = gas.price
+		}
+		if maxPrice == nil || gas.price.Cmp(maxPrice) > 0 {
+			maxPrice = gas.price
+		}
+		cost.Add(cost, gas.fee())
+		report.Transactions++
+	}
+	if report.Transactions == 0 {
+		minPrice, maxPrice = new(big.Int), new(big.Int)
+	}
+	avgPrice := new(big.Int)
+	if report.TotalGasUsed > 0 {
+		report.AvgGasUsed = report.TotalGasUsed / uint64(report.Transactions)
+		avgPrice.Div(cost, new(big.Int).SetUint64(report.TotalGasUsed))
+	}
+	report.MinGasPriceGwei = formatWeiAsGwei(minPrice)
+	report.MaxGasPriceGwei = formatWeiAsGwei(maxPrice)
+	report.AvgGasPriceGwei = formatWeiAsGwei(avgPrice)
+	report.TotalCostETH = formatWeiAsETH(cost)
+	return report
+}
+
+func (g gasReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "\nGas report (%d transactions):\n", g.Transactions)
+	fmt.Fprintf(w, "  Gas used:   min %d, max %d, avg %d, total %d\n", g.MinGasUsed, g.MaxGasUsed, g.AvgGasUsed, g.TotalGasUsed)
+	fmt.Fprintf(w, "  Gas price:  min %s, max %s, avg %s gwei\n", g.MinGasPriceGwei, g.MaxGasPriceGwei, g.AvgGasPriceGwei)
+	fmt.Fprintf(w, "  Total cost: %s ETH\n", g.TotalCostETH)
+}
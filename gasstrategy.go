@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	gasStrategyEconomy  = "economy"
+	gasStrategyStandard = "standard"
+	gasStrategyFast     = "fast"
+)
+
+// feeHistoryBlocks is the number of recent blocks the tip is taken from.
+const feeHistoryBlocks = 10
+
+// gasStrategy turns eth_feeHistory into EIP-1559 fees: the tip is the median
+// over recent blocks of the given reward percentile, the fee cap the next
+// base fee times baseFeePercent/100 plus the tip, which leaves room for
+// the base fee to rise while the transaction waits.
+type gasStrategy struct {
+	rewardPercentile float64
+	baseFeePercent   int64
+}
+
+// gasStrategies target inclusion when the network is quiet (economy), within
+// a few blocks (standard) and in the next block (fast).
+var gasStrategies = map[string]gasStrategy{
+	gasStrategyEconomy:  {rewardPercentile: 10, baseFeePercent: 125},
+	gasStrategyStandard: {rewardPercentile: 50, baseFeePercent: 200},
+	gasStrategyFast:     {rewardPercentile: 90, baseFeePercent: 300},
+}
+
+func gasStrategyNames() string {
+	names := make([]string, 0, len(gasStrategies))
+	for name := range gasStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// fees returns the tip and fee cap in wei for the next block.
+func (g gasStrategy) fees(ctx context.Context, client *ethclient.Client) (tipCap, feeCap *big.Int, err error) {
+	history, err := client.FeeHistory(ctx, feeHistoryBlocks, nil, []float64{g.rewardPercentile})
+	if err != nil {
+		return nil, nil, fmt.Errorf("eth_feeHistory: %w", err)
+	}
+	if len(history.BaseFee) == 0 {
+		return nil, nil, errors.New("eth_feeHistory returned no base fee")
+	}
+
+	var rewards []*big.Int
+	for _, reward := range history.Reward {
+		if len(reward) > 0 && reward[0] != nil {
+			rewards = append(rewards, reward[0])
+		}
+	}
+	tipCap = new(big.Int)
+	if len(rewards) > 0 {
+		sort.Slice(rewards, func(i, j int) bool { return rewards[i].Cmp(rewards[j]) < 0 })
+		tipCap.Set(rewards[len(rewards)/2])
+	}
+
+	// The last base fee is the one of the next block
+	baseFee := history.BaseFee[len(history.BaseFee)-1]
+	feeCap = new(big.Int).Mul(baseFee, big.NewInt(g.baseFeePercent))
+	feeCap.Div(feeCap, big.NewInt(100))
+	feeCap.Add(feeCap, tipCap)
+	return tipCap, feeCap, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// HexBytes is a byte string written as hex in JSON, like the fields of a
+// deposit data file. It is read with or without the 0x prefix and written
+// without it, as staking-deposit-cli does.
+type HexBytes []byte
+
+func (h HexBytes) String() string {
+	return hex.EncodeToString(h)
+}
+
+func (h HexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.String())
+}
+
+func (h *HexBytes) UnmarshalJSON(input []byte) error {
+	var s string
+	if err := json.Unmarshal(input, &s); err != nil {
+		return fmt.Errorf("hex value must be a string: %w", err)
+	}
+	b, err := decodeHex(s)
+	if err != nil {
+		return fmt.Errorf("invalid hex %q: %w", s, err)
+	}
+	*h = b
+	return nil
+}
+
+// hexField decodes the hex field name of a deposit and checks that it is
+// length bytes long.
+func hexField(name, s string, length int) (HexBytes, error) {
+	b, err := decodeHex(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	if len(b) != length {
+		return nil, fmt.Errorf("%s is %d bytes, expected %d", name, len(b), length)
+	}
+	return b, nil
+}
+
+// depositHexField is a hex field of a deposit and its length in bytes.
+type depositHexField struct {
+	name   string
+	value  *HexBytes
+	length int
+}
+
+func (d *DepositData) hexFields() []depositHexField {
+	return []depositHexField{
+		{"pubkey", &d.PubKey, pubkeyLength},
+		{"withdrawal_credentials", &d.WithdrawalCredentials, withdrawalCredentialsLength},
+		{"signature", &d.Signature, signatureLength},
+		{"deposit_data_root", &d.DepositDataRoot, rootLength},
+	}
+}
+
+// checkFieldLengths checks the lengths of the hex fields of a deposit.
+// UnmarshalJSON leaves them unchecked so that ValidateBatch reports every
+// entry of the wrong length instead of the first.
+func (d DepositData) checkFieldLengths() error {
+	for _, field := range d.hexFields() {
+		if len(*field.value) != field.length {
+			return fmt.Errorf("%s is %d bytes, expected %d", field.name, len(*field.value), field.length)
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON reads a deposit file entry. Hex fields that are missing or
+// not hex are an error naming the field; their lengths are checked by
+// ValidateBatch.
+func (d *DepositData) UnmarshalJSON(input []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(input, &raw); err != nil {
+		return err
+	}
+	for _, field := range d.hexFields() {
+		value, ok := raw[field.name]
+		if !ok {
+			return fmt.Errorf("%s is missing", field.name)
+		}
+		if err := field.value.UnmarshalJSON(value); err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
+	}
+	type entry DepositData
+	return json.Unmarshal(input, (*entry)(d))
+}
+
+// root returns the deposit data root as the bytes32 argument of a deposit.
+func (d DepositData) root() (root [32]byte) {
+	copy(root[:], d.DepositDataRoot)
+	return root
+}
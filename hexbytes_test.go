@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHexBytesJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []byte
+		err   string
+	}{
+		{input: `"00ff"`, want: []byte{0x00, 0xff}},
+		{input: `"0x00ff"`, want: []byte{0x00, 0xff}},
+		{input: `"0X00FF"`, want: []byte{0x00, 0xff}},
+		{input: `" 0x00ff "`, want: []byte{0x00, 0xff}},
+		{input: `""`, want: []byte{}},
+		{input: `"0xzz"`, err: "invalid hex"},
+		{input: `"0f0"`, err: "invalid hex"},
+		{input: `15`, err: "must be a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var h HexBytes
+			err := json.Unmarshal([]byte(tt.input), &h)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(h, tt.want) {
+				t.Errorf("decoded %x, want %x", []byte(h), tt.want)
+			}
+		})
+	}
+
+	out, err := json.Marshal(HexBytes{0x0a, 0xbc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `"0abc"` {
+		t.Errorf("marshaled %s, want \"0abc\" without the 0x prefix", out)
+	}
+}
+
+func TestDepositDataUnmarshalJSON(t *testing.T) {
+	valid := testEntry(t, 0x11, 32_000_000_000)
+	tests := []struct {
+		name   string
+		change func(entry map[string]any)
+		err    string
+	}{
+		{name: "valid", change: func(map[string]any) {}},
+		{name: "0x prefixed", change: func(entry map[string]any) {
+			for _, field := range []string{"pubkey", "withdrawal_credentials", "signature", "deposit_data_root"} {
+				entry[field] = "0x" + entry[field].(string)
+			}
+		}},
+		{name: "missing signature", change: func(entry map[string]any) {
+			delete(entry, "signature")
+		}, err: "signature is missing"},
+		{name: "invalid hex", change: func(entry map[string]any) {
+			entry["pubkey"] = strings.Repeat("zz", pubkeyLength)
+		}, err: "pubkey: invalid hex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := make(map[string]any)
+			for k, v := range valid {
+				entry[k] = v
+			}
+			tt.change(entry)
+			raw, _ := json.Marshal(entry)
+
+			var data DepositData
+			err := json.Unmarshal(raw, &data)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data.PubKey.String() != valid["pubkey"] || data.DepositDataRoot.String() != valid["deposit_data_root"] {
+				t.Errorf("decoded pubkey %s and root %s, want %s and %s", data.PubKey, data.DepositDataRoot, valid["pubkey"], valid["deposit_data_root"])
+			}
+			if data.Amount.Uint64() != 32_000_000_000 {
+				t.Errorf("decoded amount %s, want 32000000000", &data.Amount)
+			}
+		})
+	}
+}
+
+func TestCheckFieldLengths(t *testing.T) {
+	data := templateEntry()
+	if err := data.checkFieldLengths(); err != nil {
+		t.Fatalf("template entry: %v", err)
+	}
+	data.Signature = data.Signature[:95]
+	if err := data.checkFieldLengths(); err == nil || err.Error() != "signature is 95 bytes, expected 96" {
+		t.Errorf("error %v for a 95 byte signature", err)
+	}
+}
+
+// TestValidateBatchFieldLengths reads a file with fields of the wrong length:
+// they are decoded, and ValidateBatch reports every one of them so that
+// --force can drop the entries.
+func TestValidateBatchFieldLengths(t *testing.T) {
+	shortKey := testEntry(t, 0x11, 32_000_000_000)
+	shortKey["pubkey"] = shortKey["pubkey"].(string)[2:]
+	longCredentials := testEntry(t, 0x22, 32_000_000_000)
+	longCredentials["withdrawal_credentials"] = longCredentials["withdrawal_credentials"].(string) + "00"
+	raw, _ := json.Marshal([]map[string]any{shortKey, testEntry(t, 0x33, 32_000_000_000), longCredentials})
+
+	deposits, err := decodeDeposits(raw, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := ValidateBatch(deposits)
+	want := []struct {
+		index int
+		err   string
+	}{
+		{0, "pubkey is 47 bytes, expected 48"},
+		{2, "withdrawal_credentials is 33 bytes, expected 32"},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems %v, want %d", problems, len(want))
+	}
+	for i, w := range want {
+		if problems[i].Index != w.index || problems[i].Err.Error() != w.err {
+			t.Errorf("problem %d is %v, want entry %d: %s", i, problems[i], w.index, w.err)
+		}
+	}
+
+	valid, dropped, _ := dropInvalid(deposits, problems)
+	if len(valid) != 1 || valid[0].index != 1 || len(dropped) != 2 {
+		t.Errorf("kept %d and dropped %d entries, want entry 1 kept and 2 dropped", len(valid), len(dropped))
+	}
+}
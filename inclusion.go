@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// slotDuration is the time between two blocks of a post-merge chain.
+const slotDuration = 12 * time.Second
+
+// inclusionPercentiles are the tip percentiles of recent blocks a
+// transaction's tip is compared with: paying the median gets it into the
+// next block, paying the low end within a few.
+var inclusionPercentiles = []float64{10, 50}
+
+// inclusionEstimate is the expected wait of a transaction, in blocks.
+type inclusionEstimate struct {
+	blocks uint64
+	// unbounded is set when the base fee is above the fee cap and not
+	// falling, so the transaction waits for the network to calm down.
+	unbounded bool
+	baseFee   *big.Int
+}
+
+func (e inclusionEstimate) String() string {
+	if e.unbounded {
+		return fmt.Sprintf("not until the base fee of %s gwei falls below the fee cap (estimate)", formatWeiAsGwei(e.baseFee))
+	}
+	if e.blocks <= 1 {
+		return fmt.Sprintf("next block, ~%s (estimate)", slotDuration)
+	}
+	if e.blocks >= feeHistoryBlocks {
+		return fmt.Sprintf("%d or more blocks, the tip is below most recent ones (estimate)", e.blocks)
+	}
+	return fmt.Sprintf("~%d blocks, ~%s (estimate)", e.blocks, time.Duration(e.blocks)*slotDuration)
+}
+
+// estimateInclusion estimates how many blocks a transaction paying tipCap
+// and feeCap waits, from the pending base fee and the fill and tips of the
+// last feeHistoryBlocks blocks. A base fee above feeCap moves by up to 12.5%
+// per block depending on how full blocks are, so the wait is the blocks it
+// takes the base fee at the recent fill to fall to feeCap, then the blocks
+// the tip takes to win. tipCap is nil for legacy transactions, which pay
+// feeCap minus the base fee as tip.
+func estimateInclusion(ctx context.Context, client *ethclient.Client, tipCap, feeCap *big.Int) (inclusionEstimate, error) {
+	history, err := client.FeeHistory(ctx, feeHistoryBlocks, nil, inclusionPercentiles)
+	if err != nil {
+		return inclusionEstimate{}, fmt.Errorf("eth_feeHistory: %w", err)
+	}
+	if len(history.BaseFee) == 0 {
+		return inclusionEstimate{}, errors.New("eth_feeHistory returned no base fee")
+	}
+	estimate := inclusionEstimate{baseFee: history.BaseFee[len(history.BaseFee)-1]}
+
+	if feeCap.Cmp(estimate.baseFee) < 0 {
+		var fill float64
+		for _, ratio := range history.GasUsedRatio {
+			fill += ratio
+		}
+		if len(history.GasUsedRatio) > 0 {
+			fill /= float64(len(history.GasUsedRatio))
+		}
+		// The base fee changes by (fill - 50%) / 50% * 12.5% per block
+		change := 1 + (fill-0.5)/4
+		if change >= 1 {
+			estimate.unbounded = true
+			return estimate, nil
+		}
+		base, _ := new(big.Float).SetInt(estimate.baseFee).Float64()
+		target, _ := new(big.Float).SetInt(feeCap).Float64()
+		estimate.blocks = uint64(math.Ceil(math.Log(target/base) / math.Log(change)))
+		return estimate, nil
+	}
+
+	tip := new(big.Int).Sub(feeCap, estimate.baseFee)
+	if tipCap != nil && tipCap.Cmp(tip) < 0 {
+		tip = tipCap
+	}
+	var low, median []*big.Int
+	for _, reward := range history.Reward {
+		if len(reward) >= len(inclusionPercentiles) {
+			low, median = append(low, reward[0]), append(median, reward[1])
+		}
+	}
+	switch {
+	case len(median) == 0 || tip.Cmp(medianWei(median)) >= 0:
+		estimate.blocks = 1
+	case tip.Cmp(medianWei(low)) >= 0:
+		estimate.blocks = 3
+	default:
+		estimate.blocks = feeHistoryBlocks
+	}
+	return estimate, nil
+}
+
+// medianWei is the median of amounts, which it sorts.
+func medianWei(amounts []*big.Int) *big.Int {
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i].Cmp(amounts[j]) < 0 })
+	return amounts[len(amounts)/2]
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// depositIndex maps a validator pubkey to the index its deposit got in the
+// deposit contract. Index is the entry of the deposit file.
+type depositIndex struct {
+	Index        int    `json:"index"`
+	PubKey       string `json:"pubkey"`
+	DepositIndex uint64 `json:"deposit_index"`
+	TxHash       string `json:"tx_hash"`
+	Block        uint64 `json:"block"`
+}
+
+// findPubkeyDepositEvent decodes the DepositEvent for pubkey in the receipt.
+// Unlike findDepositEvent it accepts events of any emitter, so that it also
+// finds the deposits made through wrapper contracts and batches.
+func findPubkeyDepositEvent(contractABI abi.ABI, receipt *types.Receipt, pubkey HexBytes) (*depositEvent, error) {
+	event, ok := contractABI.Events["DepositEvent"]
+	if !ok {
+		return nil, errors.New("ABI has no DepositEvent")
+	}
+	for _, l := range receipt.Logs {
+		if len(l.Topics) == 0 || l.Topics[0] != event.ID {
+			continue
+		}
+		deposit, err := decodeDepositEvent(event, l)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(deposit.PubKey, pubkey) {
+			return deposit, nil
+		}
+	}
+	return nil, fmt.Errorf("no DepositEvent for %s in the receipt", shortPubkey(pubkey.String()))
+}
+
+// writeIndexMap writes the mapping as CSV if path ends in .csv, as JSON otherwise.
+func writeIndexMap(path string, mapping []depositIndex) error {
+	if !strings.EqualFold(filepath.Ext(path), ".csv") {
+		data, err := json.MarshalIndent(mapping, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0o644)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"index", "pubkey", "deposit_index", "tx_hash", "block"})
+	for _, m := range mapping {
+		w.Write([]string{strconv.Itoa(m.Index), m.PubKey, strconv.FormatUint(m.DepositIndex, 10), m.TxHash, strconv.FormatUint(m.Block, 10)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
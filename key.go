@@ -0,0 +1,35 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// parsePrivateKey works like crypto.HexToECDSA but zeroes the decoded key
+// bytes once the key has been constructed.
+func parsePrivateKey(hexKey string) (*ecdsa.PrivateKey, error) {
+	b, err := hex.DecodeString(hexKey)
+	if byteErr, ok := err.(hex.InvalidByteError); ok {
+		return nil, fmt.Errorf("invalid hex character %q in private key", byte(byteErr))
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid hex data for private key")
+	}
+	defer clear(b)
+	return crypto.ToECDSA(b)
+}
+
+// wipePrivateKey zeroes the private scalar of key in place.
+//
+// This is best effort only: the hex string read from .env is immutable and
+// cannot be cleared, and the garbage collector or big.Int arithmetic during
+// signing may have left copies of the key material elsewhere on the heap.
+func wipePrivateKey(key *ecdsa.PrivateKey) {
+	if key == nil || key.D == nil {
+		return
+	}
+	clear(key.D.Bits())
+	key.D.SetInt64(0)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ledgerEntry is one line of the ledger: a pubkey deposited successfully.
+type ledgerEntry struct {
+	PubKey string    `json:"pubkey"`
+	TxHash string    `json:"tx_hash"`
+	Block  uint64    `json:"block"`
+	Time   time.Time `json:"time"`
+}
+
+// depositLedger is the append-only list of every pubkey deposited on one
+// chain, across all runs and deposit files, so that a later run does not
+// deposit for a validator again by accident. Unlike the state file it is
+// kept per user, not per batch, and only ever grows.
+type depositLedger struct {
+	path string
+
+	mu      sync.Mutex
+	file    *os.File
+	pubkeys map[string]ledgerEntry
+}
+
+// ledgerPath is the ledger of chainID in dir, or in the user's config
+// directory if dir is empty.
+func ledgerPath(dir string, chainID *big.Int) (string, error) {
+	if dir == "" {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(config, "go-deposit", "ledger")
+	}
+	return filepath.Join(dir, chainID.String()+".jsonl"), nil
+}
+
+// openLedger reads the ledger of chainID and opens it for appending.
+func openLedger(dir string, chainID *big.Int) (*depositLedger, error) {
+	path, err := ledgerPath(dir, chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l := &depositLedger{path: path, file: file, pubkeys: make(map[string]ledgerEntry)}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry ledgerEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to parse ledger %s line %d: %w", path, line, err)
+		}
+		l.pubkeys[normalizePubkey(entry.PubKey)] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read ledger %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Lookup returns the ledger entry of pubkey.
+func (l *depositLedger) Lookup(pubkey string) (ledgerEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.pubkeys[normalizePubkey(pubkey)]
+	return entry, ok
+}
+
+// Record appends the deposit of data mined in receipt and syncs the file.
+// It is a no-op for a nil ledger.
+func (l *depositLedger) Record(data DepositData, receipt *types.Receipt) error {
+	if l == nil {
+		return nil
+	}
+	entry := ledgerEntry{
+		PubKey: data.PubKey.String(),
+		TxHash: receipt.TxHash.Hex(),
+		Block:  receipt.BlockNumber.Uint64(),
+		Time:   time.Now().UTC(),
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	l.pubkeys[entry.PubKey] = entry
+	return l.file.Sync()
+}
+
+func (l *depositLedger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// skipLedgered drops the deposits whose pubkey the ledger has.
+func skipLedgered(deposits []DepositData, ledger *depositLedger) []DepositData {
+	var remaining []DepositData
+	for _, data := range deposits {
+		if entry, ok := ledger.Lookup(data.PubKey.String()); ok {
+			fmt.Printf("Entry %d (%s) was deposited in %s at block %d according to %s, skipping\n",
+				data.index, shortPubkey(data.PubKey.String()), entry.TxHash, entry.Block, ledger.path)
+			continue
+		}
+		remaining = append(remaining, data)
+	}
+	return remaining
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values never reach a log sink.
+var sensitiveKeys = map[string]bool{
+	"private_key": true,
+	"mnemonic":    true,
+	"password":    true,
+	"pin":         true,
+}
+
+// setupLogging routes the standard logger through slog: a human-readable
+// console sink on stderr and, with --log-file, a JSON file sink that also
+// receives debug records. Secrets are redacted before reaching either sink.
+func setupLogging(cfg Config) (io.Closer, error) {
+	handlers := []slog.Handler{&consoleHandler{w: os.Stderr, mu: &sync.Mutex{}, level: slog.LevelInfo}}
+
+	var file *os.File
+	if cfg.LogFile != "" {
+		if cfg.LogRotate {
+			if err := rotateLogFile(cfg.LogFile); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		file, err = os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		handlers = append(handlers, slog.NewJSONHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	slog.SetDefault(slog.New(redactHandler{next: fanoutHandler(handlers), secrets: logSecrets(cfg)}))
+
+	if file == nil {
+		return io.NopCloser(nil), nil
+	}
+	return file, nil
+}
+
+// logSecrets returns the configured secrets that are redacted wherever they
+// appear in a log record: the private key, the PKCS#11 pin and the mnemonic
+// passphrase.
+func logSecrets(cfg Config) []string {
+	var secrets []string
+	if cfg.PrivateKey != "" {
+		secrets = append(secrets, strings.TrimPrefix(cfg.PrivateKey, "0x"))
+	}
+	if cfg.PKCS11Pin != "" {
+		secrets = append(secrets, cfg.PKCS11Pin)
+	}
+	if passphrase := os.Getenv("MNEMONIC_PASSPHRASE"); passphrase != "" {
+		secrets = append(secrets, passphrase)
+	}
+	return secrets
+}
+
+// rotateLogFile moves an existing log file aside with a timestamp suffix.
+func rotateLogFile(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	rotated := fmt.Sprintf("%s.%s", path, time.Now().Format("20060102-150405"))
+	if err := os.Rename(path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	log.Printf("Rotated previous log file to %s", rotated)
+	return nil
+}
+
+type fanoutHandler []slog.Handler
+
+func (f fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(fanoutHandler, len(f))
+	for i, h := range f {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (f fanoutHandler) WithGroup(name string) slog.Handler {
+	out := make(fanoutHandler, len(f))
+	for i, h := range f {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
+
+type redactHandler struct {
+	next    slog.Handler
+	secrets []string
+}
+
+func (h redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, h.redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = h.redactAttr(a)
+	}
+	return redactHandler{next: h.next.WithAttrs(clean), secrets: h.secrets}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{next: h.next.WithGroup(name), secrets: h.secrets}
+}
+
+func (h redactHandler) redact(s string) string {
+	for _, secret := range h.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+func (h redactHandler) redactAttr(a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.redact(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		clean := make([]any, len(group))
+		for i, g := range group {
+			clean[i] = h.redactAttr(g)
+		}
+		return slog.Group(a.Key, clean...)
+	}
+	return a
+}
+
+// consoleHandler prints records the way the standard logger does, followed by
+// any attributes as key=value pairs.
+type consoleHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	level slog.Level
+	attrs []slog.Attr
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Time.Format("2006/01/02 15:04:05 "))
+	b.WriteString(r.Message)
+	write := func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		return true
+	}
+	for _, a := range h.attrs {
+		write(a)
+	}
+	r.Attrs(write)
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &consoleHandler{w: h.w, mu: h.mu, level: h.level, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+func (h *consoleHandler) WithGroup(string) slog.Handler {
+	return h
+}
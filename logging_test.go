@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	t.Setenv("MNEMONIC_PASSPHRASE", "correct horse battery")
+	cfg := validConfig()
+	cfg.PrivateKey = "0x" + testPrivateKey
+	cfg.PKCS11Pin = "846213"
+
+	var out bytes.Buffer
+	logger := slog.New(redactHandler{next: slog.NewJSONHandler(&out, nil), secrets: logSecrets(cfg)})
+	logger.Info("key "+testPrivateKey+" pin 846213", "detail", "passphrase correct horse battery", "pin", "anything")
+
+	for _, secret := range []string{testPrivateKey, "846213", "correct horse battery", "anything"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("log contains %q: %s", secret, out.String())
+		}
+	}
+	if n := strings.Count(out.String(), redacted); n != 4 {
+		t.Errorf("%d redactions, want 4: %s", n, out.String())
+	}
+}
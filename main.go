@@ -2,202 +2,991 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log"
+	"log/slog"
 	"math/big"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/joho/godotenv"
 )
 
-const (
-	contractAddress = "0x4242424242424242424242424242424242424242"
-	gasLimit        = 300000
-)
+// maxNonceRecoveries caps how many times a "nonce too low" send is retried.
+const maxNonceRecoveries = 1
 
 type DepositData struct {
-	Amount                big.Int `json:"amount"`
-	PubKey                string  `json:"pubkey"`
-	WithdrawalCredentials string  `json:"withdrawal_credentials"`
-	Signature             string  `json:"signature"`
-	DepositDataRoot       string  `json:"deposit_data_root"`
+	Amount                big.Int  `json:"amount"`
+	PubKey                HexBytes `json:"pubkey"`
+	WithdrawalCredentials HexBytes `json:"withdrawal_credentials"`
+	Signature             HexBytes `json:"signature"`
+	DepositDataRoot       HexBytes `json:"deposit_data_root"`
+	// DepositMessageRoot is the root of the deposit without the signature,
+	// written by staking-deposit-cli; the signature is checked against it.
+	DepositMessageRoot string `json:"deposit_message_root,omitempty"`
+	ForkVersion        string `json:"fork_version,omitempty"`
+	// GenesisValidatorsRoot and NetworkName are written by some key
+	// generators for the network the deposit is meant for.
+	GenesisValidatorsRoot string `json:"genesis_validators_root,omitempty"`
+	NetworkName           string `json:"network_name,omitempty"`
+	// GasFeeCapGwei and GasTipCapGwei override the fees of this deposit.
+	GasFeeCapGwei json.Number `json:"gas_fee_cap_gwei,omitempty"`
+	GasTipCapGwei json.Number `json:"gas_tip_cap_gwei,omitempty"`
+
+	// index is the position of the entry in the deposit file.
+	index int
+	// amountChanged marks a top-up whose amount was changed in review, so
+	// that its signature no longer covers the amount.
+	amountChanged bool
 }
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("Error loading .env file")
+	if len(os.Args) == 2 && os.Args[1] == "template" {
+		printTemplate()
+		return
 	}
 
-	privateKeyHex := os.Getenv("PRIVATE_KEY")
-	if privateKeyHex == "" {
-		log.Fatalf("PRIVATE_KEY not set in .env file")
+	// plan and apply take the same flags; apply is the default
+	// cancel takes a transaction hash and broadcast a file of signed
+	// transactions instead of a deposit file, doctor an optional one
+	subcommand := "apply"
+	if len(os.Args) > 1 && (os.Args[1] == "plan" || os.Args[1] == "apply" || os.Args[1] == "cancel" || os.Args[1] == "broadcast" || os.Args[1] == "doctor") {
+		subcommand = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
 	}
+	planOnly := subcommand == "plan"
 
-	rpcUrl := os.Getenv("RPC_URL")
-	if rpcUrl == "" {
-		log.Fatalf("RPC_URL not set in .env file")
+	cfg := DefaultConfig()
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: go-deposit [plan|apply] [flags] <deposit_data.json> | go-deposit cancel [flags] <tx_hash> | go-deposit broadcast [flags] <raw_txs> | go-deposit doctor [flags] [deposit_data.json] | go-deposit template\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	loadEnvFile(cfg.EnvFile)
+	cfg.LoadEnv()
+
+	var runDir string
+	if cfg.OutputDir != "" {
+		started := time.Now()
+		dir, err := cfg.prepareOutputDir(started)
+		if err != nil {
+			log.Fatalf("Failed to create output directory: %v", err)
+		}
+		runDir = dir
+		if err := writeManifest(runDir, started, subcommand, flag.Arg(0), flag.CommandLine); err != nil {
+			log.Fatalf("Failed to write run manifest: %v", err)
+		}
 	}
 
-	abiFile, err := os.ReadFile("abi.json")
+	logFile, err := setupLogging(cfg)
 	if err != nil {
-		log.Fatalf("Failed to read abi.json file: %v", err)
+		log.Fatalf("Failed to set up logging: %v", err)
 	}
+	defer logFile.Close()
 
-	// Load the contract ABI
-	contractABI, err := abi.JSON(strings.NewReader(string(abiFile)))
-	if err != nil {
-		log.Fatalf("Failed to parse contract ABI: %v", err)
+	if cfg.DepositProfileFile != "" {
+		if err := registerNetworkProfile(cfg.DepositProfileFile); err != nil {
+			log.Fatalf("Failed to load %s: %v", cfg.DepositProfileFile, err)
+		}
 	}
 
-	if len(os.Args) != 2 {
-		log.Fatalf("Usage: go-deposit <deposit_data.json>")
+	if subcommand == "doctor" {
+		if flag.NArg() > 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if failed := runDoctor(cfg, flag.Arg(0)); failed > 0 {
+			os.Exit(1)
+		}
+		return
 	}
-	depositDataFilePath := os.Args[1]
 
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
-	if err != nil {
-		log.Fatalf("Invalid private key: %v", err)
+	if subcommand == "broadcast" {
+		if err := cfg.ValidateBroadcast(); err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+		if flag.NArg() != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := runBroadcast(cfg, flag.Arg(0)); err != nil {
+			log.Fatalf("Broadcast failed: %v", err)
+		}
+		return
 	}
 
-	client, err := ethclient.Dial(rpcUrl)
-	if err != nil {
-		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	// The transactions of a bundle are only sent once all are signed
+	if cfg.TxSendBundle != "" {
+		cfg.NoWait = true
+	}
+	if cfg.SignOnly {
+		cfg.Output = outputBundle
+	}
+
+	// Keep stdout for the bundle alone
+	bundleOut := os.Stdout
+	if cfg.Output == outputBundle {
+		os.Stdout = os.Stderr
+	}
+	if runDir != "" {
+		fmt.Printf("Run artifacts are written to %s\n", runDir)
+	}
+
+	if subcommand == "cancel" {
+		if flag.NArg() != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := runCancel(cfg, flag.Arg(0)); err != nil {
+			log.Fatalf("Failed to cancel: %v", err)
+		}
+		return
+	}
+
+	// With --abi-from-explorer abi.json is optional and only adds the
+	// canonical deposit contract entries missing from a wrapper's ABI
+	var contractABI abi.ABI
+	abiFile, err := os.ReadFile("abi.json")
+	if err != nil && !cfg.AbiFromExplorer {
+		log.Fatalf("Failed to read abi.json file: %v", err)
+	}
+	if err == nil {
+		// Load the contract ABI
+		contractABI, err = abi.JSON(strings.NewReader(string(abiFile)))
+		if err != nil {
+			log.Fatalf("Failed to parse contract ABI: %v", err)
+		}
+	}
+
+	var call depositCall
+	var batch batchCall
+	setupCalls := func() {
+		call, err = newDepositCall(contractABI, cfg.DepositMethod, cfg.DepositArgs)
+		if err != nil {
+			log.Fatalf("Invalid --deposit-method: %v", err)
+		}
+		if cfg.BatchSize > 1 {
+			batch, err = newBatchCall(contractABI, cfg.BatchMethod)
+			if err != nil {
+				log.Printf("Warning: sending one transaction per deposit, --batch-size needs a batch deposit method: %v", err)
+				cfg.BatchSize = 1
+			}
+		}
+	}
+	if !cfg.AbiFromExplorer {
+		setupCalls()
+	}
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
 	}
+	depositDataFilePath := flag.Arg(0)
 
 	file, err := os.ReadFile(depositDataFilePath)
 	if err != nil {
 		log.Fatalf("Failed to read deposit_data.json file: %v", err)
 	}
 
-	var depositData []DepositData
-	if err := json.Unmarshal(file, &depositData); err != nil {
+	fieldMap, err := parseFieldMap(cfg.FieldMap)
+	if err != nil {
+		log.Fatalf("Invalid --field-map: %v", err)
+	}
+	depositData, err := decodeDeposits(file, fieldMap)
+	if err != nil {
 		log.Fatalf("Failed to unmarshal deposit data: %v", err)
 	}
 
 	fmt.Printf("Deposit data has %d entries\n", len(depositData))
+	fileEntries := depositData
+	if len(depositData) == 0 {
+		if !cfg.AllowEmpty {
+			log.Fatalf("Deposit file %s has no entries, check the path or pass --allow-empty", depositDataFilePath)
+		}
+		fmt.Printf("Nothing to deposit\n")
+		return
+	}
+	summary := newRunSummary()
+
+	if cfg.ResubmitFailed != "" {
+		report, err := readReport(cfg.ResubmitFailed)
+		if err != nil {
+			log.Fatalf("Failed to read report: %v", err)
+		}
+		retry, carried, missing, err := selectFailed(depositData, report)
+		if err != nil {
+			log.Fatalf("Report %s does not match the deposit file: %v", cfg.ResubmitFailed, err)
+		}
+		if missing > 0 {
+			log.Printf("Warning: %d entries of the deposit file are not in %s and are not submitted", missing, cfg.ResubmitFailed)
+		}
+		for _, e := range carried {
+			if e.Status == outcomeInFlight {
+				log.Printf("Warning: entry %d was in flight as %s and is not resubmitted, check its receipt", e.Index, e.TxHash)
+			}
+			summary.Carry(e)
+		}
+		depositData = retry
+		if len(depositData) == 0 {
+			fmt.Printf("No failed entries in %s\n", cfg.ResubmitFailed)
+			return
+		}
+		fmt.Printf("Resubmitting %d failed entries from %s\n", len(depositData), cfg.ResubmitFailed)
+
+		// Conditions may have changed since the failure: estimate the gas again
+		// and update the report in place
+		if cfg.ParallelGasEstimation == 0 && cfg.BatchSize == 1 {
+			cfg.ParallelGasEstimation = cfg.ReceiptWorkers
+		}
+		if cfg.SummaryFile == "" {
+			cfg.SummaryFile = cfg.ResubmitFailed
+		}
+	}
 
-	for _, data := range depositData {
-		submitSingleDepositData(data, contractABI, client, privateKey)
+	if suspicious := ethLikeAmounts(depositData, cfg.UnitsThreshold); len(suspicious) > 0 {
+		log.Printf("WARNING: amounts are in GWEI, not ETH: an amount of 32 deposits 32 gwei, not 32 ETH (32 ETH = 32000000000)")
+		for _, data := range suspicious {
+			log.Printf("WARNING: entry %d (%s) deposits %s gwei, did you mean %s ETH?", data.index, shortPubkey(data.PubKey.String()), data.Amount.String(), data.Amount.String())
+		}
+		if !cfg.ConfirmUnits {
+			log.Fatalf("%d amounts look like ETH, fix the deposit file or pass --confirm-units", len(suspicious))
+		}
 	}
-}
 
-func submitSingleDepositData(data DepositData, abi abi.ABI, client *ethclient.Client, privateKey *ecdsa.PrivateKey) {
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatalf("Error casting public key to ECDSA")
+	if err := checkEntryFees(cfg, depositData); err != nil {
+		log.Fatalf("Invalid deposit fees: %v", err)
 	}
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
 
-	chainID, err := client.ChainID(context.Background())
-	if err != nil {
-		log.Fatalf("Failed to get chain ID: %v", err)
+	if deduped, duplicates := dedupeDeposits(depositData); len(duplicates) > 0 {
+		for _, d := range duplicates {
+			log.Printf("Warning: entry %d duplicates entry %d (pubkey %s, same amount)", d.Index, d.FirstIndex, d.PubKey)
+		}
+		if cfg.Dedupe {
+			summary.Skipped(depositData, deduped, "duplicate entry")
+			depositData = deduped
+			fmt.Printf("Dropped %d duplicate entries\n", len(duplicates))
+		} else {
+			log.Printf("Warning: %d duplicate entries are invalid and stop the run, use --dedupe to drop them", len(duplicates))
+		}
 	}
-	fmt.Printf("Chain ID: %d\n", chainID)
 
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
-	if err != nil {
-		log.Fatalf("Failed to get nonce: %v", err)
+	if cfg.PubkeyFilter != "" {
+		wanted, err := parsePubkeyFilter(cfg.PubkeyFilter)
+		if err != nil {
+			log.Fatalf("Failed to read pubkey filter: %v", err)
+		}
+		filtered, missing := filterByPubkey(depositData, wanted)
+		summary.Skipped(depositData, filtered, "not in --pubkey-filter")
+		depositData = filtered
+		for _, pubkey := range missing {
+			log.Printf("Warning: pubkey %s from the filter was not found in the deposit data", pubkey)
+		}
+		fmt.Printf("Pubkey filter selected %d entries\n", len(depositData))
 	}
 
-	// Suggest gas fees for EIP-1559
-	tipCap, err := client.SuggestGasTipCap(context.Background())
-	if err != nil {
-		log.Fatalf("Failed to get gas tip cap: %v", err)
+	var state *depositState
+	if cfg.StateFile != "" {
+		state, err = openState(cfg.StateFile)
+		if err != nil {
+			log.Fatalf("Failed to open state file: %v", err)
+		}
+		defer state.Close()
+
+		// Confirmed deposits need no RPC calls at all, and when nothing is left
+		// there is no reason to connect to the node.
+		unconfirmed := skipConfirmed(depositData, state)
+		summary.Skipped(depositData, unconfirmed, "confirmed in state file")
+		depositData = unconfirmed
+		if len(depositData) == 0 {
+			fmt.Printf("All deposits are already confirmed in %s\n", cfg.StateFile)
+			return
+		}
 	}
 
-	feeCap, err := client.SuggestGasPrice(context.Background())
-	if err != nil {
-		log.Fatalf("Failed to get gas fee cap: %v", err)
+	if cfg.Interactive {
+		reviewed, err := reviewDeposits(depositData, depositedLookup(cfg, contractABI))
+		if err != nil {
+			log.Fatalf("Nothing submitted: %v", err)
+		}
+		summary.Skipped(depositData, reviewed, "excluded in review")
+		depositData = reviewed
+		if len(depositData) == 0 {
+			fmt.Printf("No deposits selected\n")
+			return
+		}
 	}
 
-	pubKeyBytes, err := hex.DecodeString(data.PubKey)
-	if err != nil {
-		log.Fatalf("Failed to decode pubkey: %v", err)
+	if cfg.MaxTotal != nil {
+		total := totalGwei(depositData)
+		if total.Cmp(cfg.MaxTotal) > 0 {
+			if !cfg.Force {
+				log.Fatalf("Deposits add up to %s ETH, more than --max-total-eth %s ETH; use --force to deposit anyway",
+					formatGweiAsETH(total), formatGweiAsETH(cfg.MaxTotal))
+			}
+			log.Printf("Warning: deposits add up to %s ETH, more than --max-total-eth %s ETH", formatGweiAsETH(total), formatGweiAsETH(cfg.MaxTotal))
+		}
 	}
 
-	withdrawalCredentialsBytes, err := hex.DecodeString(data.WithdrawalCredentials)
-	if err != nil {
-		log.Fatalf("Failed to decode withdrawal credentials: %v", err)
+	// Every offline check runs on the whole file before the node is contacted
+	if cfg.ExpectedWithdrawalAddress != "" {
+		if mismatched := withdrawalAddressMismatches(depositData, common.HexToAddress(cfg.ExpectedWithdrawalAddress)); len(mismatched) > 0 {
+			for _, m := range mismatched {
+				log.Printf("WRONG WITHDRAWAL ADDRESS: %v", m)
+			}
+			if !cfg.Force {
+				log.Fatalf("%d entries withdraw to another address than --expected-withdrawal-address, nothing was sent; use --force to deposit anyway", len(mismatched))
+			}
+			log.Printf("WARNING: --force deposits %d entries with another withdrawal address", len(mismatched))
+		}
+	}
+	problems := ValidateBatch(depositData)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("Invalid %v", problem)
+		}
+		if !cfg.Force {
+			log.Fatalf("%d problems in %d entries, nothing was sent; fix the deposit file or use --force to skip invalid entries", len(problems), len(depositData))
+		}
+	}
+	// Interactive runs show the whole run before the first confirmation
+	if !planOnly && !cfg.PreviewCalldataHash && (cfg.PrintDepositSummaryBefore || !cfg.Yes) {
+		printPreflight(os.Stdout, fileEntries, depositData, summary.Results(), problems)
+	}
+	invalid := 0
+	if len(problems) > 0 {
+		var dropped []DepositData
+		var errs map[int]error
+		depositData, dropped, errs = dropInvalid(depositData, problems)
+		indices := make([]string, len(dropped))
+		for i, data := range dropped {
+			indices[i] = strconv.Itoa(data.index)
+			summary.Failed(data, outcomeFailedValidation, common.Hash{}, errs[data.index])
+		}
+		invalid += len(dropped)
+		log.Printf("WARNING: --force drops %d invalid entries: %s", len(dropped), strings.Join(indices, ", "))
 	}
 
-	signatureBytes, err := hex.DecodeString(data.Signature)
-	if err != nil {
-		log.Fatalf("Failed to decode signature: %v", err)
+	if cfg.PreviewCalldataHash {
+		if err := calldataHashes(call, depositData); err != nil {
+			log.Fatalf("Failed to pack calldata: %v", err)
+		}
+		return
 	}
 
-	ddrBytes, err := hex.DecodeString(data.DepositDataRoot)
+	signer, err := newTxSigner(cfg)
 	if err != nil {
-		log.Fatalf("Failed to decode deposit data root: %v", err)
+		log.Fatalf("Invalid signing key: %v", err)
 	}
-	var ddrArray [32]byte
-	copy(ddrArray[:], ddrBytes[:32])
 
-	// Pack the arguments
-	packedData, err := abi.Pack("deposit", pubKeyBytes, withdrawalCredentialsBytes, signatureBytes, ddrArray)
+	client, err := dialClient(cfg)
 	if err != nil {
-		log.Fatalf("Failed to pack arguments: %v", err)
-	}
-
-	// GWEI to WEI
-	amountWei := data.Amount.Mul(&data.Amount, big.NewInt(1e9))
-
-	// Create EIP-1559 transaction
-	depositAddress := common.HexToAddress(contractAddress)
-	tx := types.NewTx(&types.DynamicFeeTx{
-		ChainID:   chainID,
-		Nonce:     nonce,
-		GasTipCap: tipCap,
-		GasFeeCap: feeCap,
-		Gas:       gasLimit,
-		To:        &depositAddress,
-		Value:     amountWei,
-		Data:      packedData,
-	})
-
-	txJS, err := json.MarshalIndent(tx, "", "  ")
+		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+	}
+
+	chainID, err := resolveChainID(context.Background(), client, cfg.ChainID)
 	if err != nil {
-		log.Fatalf("Failed to marshal transaction: %v", err)
+		log.Fatalf("Failed to resolve chain ID: %v", err)
 	}
-	fmt.Printf("Transaction: %s\n\n", string(txJS))
-	fmt.Printf("Confirm transaction? (y/n): ")
-	var confirm string
-	fmt.Scanln(&confirm)
-	if confirm != "y" {
-		log.Fatalf("Transaction cancelled")
+	n, knownNetwork := networkByChainID(chainID)
+	if knownNetwork {
+		fmt.Printf("Chain ID: %d (%s)\n", chainID, n.Name)
+	} else {
+		fmt.Printf("Chain ID: %d\n", chainID)
 	}
 
-	signer := types.LatestSignerForChainID(chainID)
-	signedTx, err := types.SignTx(tx, signer, privateKey)
-	if err != nil {
-		log.Fatalf("Failed to sign transaction: %v", err)
+	// The loudest check first: deposits made for another chain are lost
+	if mismatched := chainIDMismatches(depositData, chainID); len(mismatched) > 0 {
+		for _, m := range mismatched {
+			log.Printf("WRONG NETWORK: entry %d (%s) has %s %q of %s (chain ID %d), the node at RPC_URL is on chain ID %d",
+				m.data.index, shortPubkey(m.data.PubKey.String()), m.field, m.value, m.network.Name, m.chainID, chainID)
+		}
+		if !cfg.AllowNetworkMismatch {
+			log.Fatalf("%d entries are for another chain than the node's, nothing was sent; check RPC_URL and the deposit file, or use --allow-network-mismatch to deposit anyway", len(mismatched))
+		}
+		log.Printf("WARNING: --allow-network-mismatch overrides the chain of %d entries, depositing them on chain ID %d", len(mismatched), chainID)
 	}
 
-	if err = client.SendTransaction(context.Background(), signedTx); err != nil {
-		log.Fatalf("Failed to send transaction: %v", err)
+	if knownNetwork {
+		if mismatched := genesisRootMismatches(depositData, n); len(mismatched) > 0 {
+			for _, data := range mismatched {
+				log.Printf("WARNING: entry %d (%s) has genesis_validators_root %s, %s has %s: it was generated for another network",
+					data.index, shortPubkey(data.PubKey.String()), "0x"+data.GenesisValidatorsRoot, n.Name, n.GenesisValidatorsRoot.Hex())
+			}
+			if !cfg.AllowNetworkMismatch {
+				log.Fatalf("%d entries were generated for another network than %s, nothing was sent; use --allow-network-mismatch to deposit anyway", len(mismatched), n.Name)
+			}
+			log.Printf("WARNING: --allow-network-mismatch deposits %d entries generated for another network on %s", len(mismatched), n.Name)
+		}
+	}
+
+	roots, badRoots := verifyMessageRoots(depositData, n, knownNetwork)
+	if len(badRoots) > 0 {
+		for _, problem := range badRoots {
+			log.Printf("Invalid %v", problem)
+		}
+		if !cfg.Force {
+			log.Fatalf("%d entries failed the deposit_message_root or signature check, nothing was sent; use --force to deposit anyway", len(badRoots))
+		}
+		log.Printf("WARNING: --force deposits %d entries that failed the deposit_message_root or signature check", len(badRoots))
+	}
+	if roots.roots > 0 || len(badRoots) > 0 {
+		fmt.Printf("Verified the deposit_message_root of %d entries and the signature of %d, %d entries have no deposit_message_root\n",
+			roots.roots, roots.signatures, roots.missing)
+	}
+	if roots.changed > 0 {
+		fmt.Printf("The deposit_message_root and signature of %d top-ups with a changed amount were not checked\n", roots.changed)
 	}
 
-	fmt.Printf("Transaction sent: %s, waiting for the receipt...\n\n", signedTx.Hash().Hex())
+	var ledger *depositLedger
+	if !cfg.IgnoreLedger {
+		ledger, err = openLedger(cfg.LedgerDir, chainID)
+		if err != nil {
+			log.Fatalf("Failed to open the deposit ledger: %v", err)
+		}
+		defer ledger.Close()
+
+		unledgered := skipLedgered(depositData, ledger)
+		summary.Skipped(depositData, unledgered, "deposited in an earlier run")
+		depositData = unledgered
+		if len(depositData) == 0 {
+			fmt.Printf("All deposits were already made according to %s, use --ignore-ledger to deposit again\n", ledger.path)
+			return
+		}
+	}
 
-	receipt, err := bind.WaitMined(context.Background(), client, signedTx)
-	if err != nil {
-		log.Fatalf("Failed to get transaction receipt: %v", err)
+	// A custom address could be anything: make sure it answers like a deposit contract
+	customContract := cfg.ResolveContract(n, knownNetwork)
+	if cfg.WarnTip == nil {
+		cfg.WarnTip = n.WarnTipThreshold()
+	}
+	depositAddress := cfg.DepositAddress()
+	if cfg.AbiFromExplorer {
+		fetched, cached, err := explorerABI(context.Background(), cfg.ExplorerAPIURL, cfg.ExplorerAPIKey, chainID, depositAddress)
+		if err != nil {
+			log.Fatalf("Failed to fetch the ABI of %s: %v", depositAddress.Hex(), err)
+		}
+		if _, ok := fetched.Methods[cfg.DepositMethod]; !ok {
+			log.Fatalf("Verified ABI of %s has no %s method, check --contract and --deposit-method", depositAddress.Hex(), cfg.DepositMethod)
+		}
+		if cached {
+			fmt.Printf("Using the cached verified ABI of %s\n", depositAddress.Hex())
+		} else {
+			fmt.Printf("Fetched the verified ABI of %s\n", depositAddress.Hex())
+		}
+		contractABI = mergeABI(contractABI, fetched)
+		setupCalls()
+	}
+	checkAddress, checkable := depositCheckAddress(cfg, n, knownNetwork)
+	if !checkable {
+		if cfg.VerifyAfterSubmit {
+			log.Fatalf("No deposit contract is known for chain ID %d, --verify-after-submit cannot check the deposits made through --deposit-method %s", chainID, cfg.DepositMethod)
+		}
+		log.Printf("Warning: no deposit contract is known for chain ID %d, the checks of the contract behind --deposit-method %s are skipped", chainID, cfg.DepositMethod)
+	}
+	if customContract && checkable {
+		root, err := readDepositRoot(context.Background(), client, checkAddress)
+		if err != nil {
+			log.Fatalf("Refusing to submit to %s: %v", depositAddress.Hex(), err)
+		}
+		fmt.Printf("Deposit contract %s has deposit root %s\n", checkAddress.Hex(), root.Hex())
+	} else {
+		fmt.Printf("Deposit contract: %s\n", depositAddress.Hex())
+	}
+	var codeHash common.Hash
+	var depositCount uint64
+	if checkable {
+		codeHash, depositCount, err = checkDepositContract(context.Background(), client, checkAddress, common.HexToHash(cfg.ContractCodeHash))
+		if err != nil {
+			log.Fatalf("Refusing to submit to %s, %s does not behave like a deposit contract: %v", depositAddress.Hex(), checkAddress.Hex(), err)
+		}
+		fmt.Printf("Deposit contract code hash %s, %d deposits so far\n", codeHash.Hex(), depositCount)
+	}
+	if cfg.DepositMethod != defaultDepositMethod {
+		codeHash, err = codeHashAt(context.Background(), client, depositAddress)
+		if err != nil {
+			log.Fatalf("Refusing to submit to %s: %v", depositAddress.Hex(), err)
+		}
+		fmt.Printf("Wrapper contract %s has code hash %s\n", depositAddress.Hex(), codeHash.Hex())
+	}
+	if customContract && !planOnly && !confirmContract(cfg, depositAddress, codeHash, depositCount) {
+		log.Fatalf("Custom deposit contract %s was not confirmed, nothing was sent", depositAddress.Hex())
 	}
 
-	receiptJSON, err := json.MarshalIndent(receipt, "", "  ")
+	txType, err := resolveTxType(context.Background(), client, cfg.TxType)
 	if err != nil {
-		log.Fatalf("Failed to marshal receipt: %v", err)
+		log.Fatalf("Failed to select transaction type: %v", err)
+	}
+	fmt.Printf("Using %s transactions\n", txType)
+
+	var links *explorerLinks
+	if cfg.Explorer || cfg.ExplorerURL != "" {
+		if !knownNetwork && cfg.ExplorerURL == "" {
+			log.Printf("Warning: no block explorer known for chain ID %d, use --explorer-url", chainID)
+		}
+		l := newExplorerLinks(n, cfg.ExplorerURL)
+		links = &l
+	}
+
+	if cfg.DumpSigningData != "" {
+		if err := dumpSigningData(cfg.DumpSigningData, depositData, n, knownNetwork); err != nil {
+			log.Fatalf("Failed to dump signing data: %v", err)
+		}
+		fmt.Printf("Signing data written to %s\n", cfg.DumpSigningData)
+	}
+
+	submitter := NewSubmitter(cfg, call, client, signer, chainID, txType, n.DepositProfile(), state)
+	if cfg.WebhookURL != "" {
+		hook := newWebhook(cfg.WebhookURL)
+		submitter.OnBeforeSubmit = hook.BeforeSubmit
+		submitter.OnAfterSubmit = hook.AfterSubmit
+	}
+
+	if planOnly {
+		existing := make(map[string]bool)
+		if checkable {
+			existing, err = depositedPubkeys(context.Background(), client, depositContractABI, checkAddress, cfg.PlanFromBlock)
+			if err != nil {
+				log.Fatalf("Failed to look up existing deposits: %v", err)
+			}
+		} else {
+			log.Printf("Warning: existing deposits are not looked up, every entry is planned as new")
+		}
+		gasPrice := cfg.GasFeeCap
+		if gasPrice == nil {
+			gasPrice, err = client.SuggestGasPrice(context.Background())
+			if err != nil {
+				log.Fatalf("Failed to get gas price: %v", err)
+			}
+		}
+		printPlan(os.Stdout, submitter.Plan(context.Background(), depositData, existing, gasPrice))
+		submitter.printNonces(context.Background(), transactionCount(len(depositData), cfg.BatchSize))
+		return
+	}
+	if cfg.PrintNonces {
+		submitter.printNonces(context.Background(), transactionCount(len(depositData), cfg.BatchSize))
+	}
+
+	var notify *notifier
+	if cfg.NotifyURL != "" {
+		notify = &notifier{hook: newWebhook(cfg.NotifyURL), failures: cfg.NotifyFailures}
+	}
+	var crossVerify *crossVerifier
+	if len(cfg.CrossVerify) > 0 {
+		crossVerify, err = newCrossVerifier(context.Background(), cfg, chainID)
+		if err != nil {
+			log.Fatalf("Failed to set up --cross-verify: %v", err)
+		}
+		fmt.Printf("Cross-verifying with %d providers\n", len(cfg.CrossVerify))
+	}
+	var txpool *txpoolReporter
+	if cfg.TxpoolStatus {
+		txpool = &txpoolReporter{client: client, from: signer.Address()}
+	}
+	var metrics *runMetrics
+	if cfg.MetricsAddr != "" {
+		metrics = newRunMetrics()
+		stop, err := serveMetrics(cfg.MetricsAddr, metrics)
+		if err != nil {
+			log.Fatalf("Failed to serve metrics: %v", err)
+		}
+		defer stop()
+		fmt.Printf("Serving metrics on http://%s/metrics\n", cfg.MetricsAddr)
+	}
+	var indexMap []depositIndex
+	var signedTxs []*types.Transaction
+	var signedIndices [][]int
+	report := func() {
+		if cfg.Output == outputBundle {
+			if err := writeBundle(bundleOut, signedTxs, signedIndices); err != nil {
+				log.Printf("Warning: failed to write the transaction bundle: %v", err)
+			}
+		}
+
+		if cfg.IndexMap != "" {
+			if err := writeIndexMap(cfg.IndexMap, indexMap); err != nil {
+				log.Printf("Warning: failed to write index map: %v", err)
+			} else {
+				fmt.Printf("Deposit indices written to %s\n", cfg.IndexMap)
+			}
+		}
+
+		fmt.Printf("\nSummary:\n")
+		summary.Print(os.Stdout, cfg.SummarySort)
+		if cfg.GasReport {
+			summary.GasReport().Print(os.Stdout)
+		}
+		if cfg.SummaryFile != "" {
+			if err := summary.Write(cfg.SummaryFile, cfg.SummarySort, cfg.GasReport); err != nil {
+				log.Printf("Warning: failed to write summary file: %v", err)
+			}
+		}
+		notify.Completed(summary, chainID)
+	}
+
+	var unverified, reverted []string
+	finish := func(data DepositData, tx *types.Transaction, receipt *types.Receipt) {
+		crossVerify.CheckReceipt(context.Background(), receipt)
+		if links != nil && receipt.Status == types.ReceiptStatusSuccessful {
+			links.Print(receipt.TxHash, data.PubKey.String())
+		}
+
+		status, detail := statusConfirmed, ""
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			status = statusReverted
+			reverted = append(reverted, receipt.TxHash.Hex())
+			detail = submitter.ReplayRevert(context.Background(), tx, receipt)
+			log.Printf("Deposit %d REVERTED (status %d) in %s: %s", data.index, receipt.Status, receipt.TxHash.Hex(), detail)
+			notify.Failure(data, receipt.TxHash.Hex(), "reverted: "+detail)
+		} else if cfg.VerifyAfterSubmit {
+			event, err := verifyDeposit(context.Background(), client, depositContractABI, checkAddress, data, receipt)
+			if err != nil {
+				log.Printf("Deposit %s mined but failed verification: %v", receipt.TxHash.Hex(), err)
+				unverified = append(unverified, receipt.TxHash.Hex())
+				notify.Failure(data, receipt.TxHash.Hex(), "verification failed: "+err.Error())
+				status, detail = statusUnverified, err.Error()
+			} else {
+				fmt.Printf("Deposit %s verified with deposit index %d\n", receipt.TxHash.Hex(), event.Index)
+				status, detail = statusVerified, fmt.Sprintf("deposit index %d", event.Index)
+			}
+		}
+
+		if cfg.IndexMap != "" && receipt.Status == types.ReceiptStatusSuccessful {
+			event, err := findPubkeyDepositEvent(contractABI, receipt, data.PubKey)
+			if err != nil {
+				log.Printf("Warning: no deposit index for %s: %v", receipt.TxHash.Hex(), err)
+			} else {
+				indexMap = append(indexMap, depositIndex{
+					Index:        data.index,
+					PubKey:       data.PubKey.String(),
+					DepositIndex: event.Index,
+					TxHash:       receipt.TxHash.Hex(),
+					Block:        receipt.BlockNumber.Uint64(),
+				})
+			}
+		}
+
+		if receipt.Status == types.ReceiptStatusSuccessful {
+			fmt.Printf("Deposit %d SUCCEEDED (status %d) in block %d\n", data.index, receipt.Status, receipt.BlockNumber)
+			if err := ledger.Record(data, receipt); err != nil {
+				log.Printf("Warning: failed to add deposit %d to the ledger: %v", data.index, err)
+			}
+		}
+		if cfg.ReceiptOutputDir != "" {
+			if path, err := writeReceiptFile(cfg.ReceiptOutputDir, data, tx, receipt); err != nil {
+				log.Printf("Warning: failed to save the receipt of deposit %d: %v", data.index, err)
+			} else {
+				fmt.Printf("Receipt of deposit %d saved to %s\n", data.index, path)
+			}
+		}
+		summary.Mined(data, status, receipt, detail)
+		metrics.Mined(status, receipt)
+		submitter.AfterSubmit(data, receipt, nil)
+		slog.Debug("deposit finished", "pubkey", data.PubKey, "tx", receipt.TxHash.Hex(), "block", receipt.BlockNumber.Uint64(), "status", status)
+
+		if state != nil {
+			record := depositRecord{Index: data.index, PubKey: data.PubKey.String(), TxHash: receipt.TxHash.Hex(), Status: status}
+			if err := state.Record(data.DepositDataRoot.String(), record); err != nil {
+				log.Fatalf("Failed to write state file: %v", err)
+			}
+		}
+	}
+
+	if cfg.StartAtBlock > 0 || !cfg.StartAtTime.IsZero() {
+		if _, err := waitForStart(context.Background(), client, cfg.StartAtBlock, cfg.StartAtTime, cfg.Poll()); err != nil {
+			log.Fatalf("Failed to wait for the start: %v", err)
+		}
+	}
+
+	if cfg.ParallelGasEstimation > 0 {
+		fmt.Printf("Estimating gas of %d deposits...\n", len(depositData))
+		failures := submitter.EstimateAll(context.Background(), depositData, cfg.ParallelGasEstimation)
+		for _, f := range failures {
+			log.Printf("Warning: gas estimation of entry %d (%s) failed: %v", f.data.index, shortPubkey(f.data.PubKey.String()), f.err)
+		}
+		if len(failures) > 0 {
+			if !cfg.Force {
+				log.Fatalf("Gas estimation failed for %d of %d entries, nothing was sent; use --force to submit them with --gas-limit", len(failures), len(depositData))
+			}
+			log.Printf("Warning: submitting %d entries without an estimate with --gas-limit %d", len(failures), cfg.GasLimit)
+		}
+	}
+
+	if cfg.SimulateAll {
+		fmt.Printf("Simulating %d deposits...\n", len(depositData))
+		if failures := submitter.SimulateAll(context.Background(), depositData); len(failures) > 0 {
+			for _, f := range failures {
+				log.Printf("Simulation of entry %d (%s) failed: %v", f.data.index, shortPubkey(f.data.PubKey.String()), f.err)
+			}
+			log.Fatalf("Simulation failed for %d of %d entries, nothing was sent", len(failures), len(depositData))
+		}
+		fmt.Printf("All %d simulations succeeded\n", len(depositData))
+	}
+
+	// inFlight records deposits whose transaction was sent but whose
+	// receipt could not be fetched. They are not failed: sending them again
+	// could deposit twice.
+	inFlight := func(deposits []DepositData, tx *types.Transaction, err error) {
+		// A transaction the node no longer knows was dropped and not mined
+		if _, _, txErr := client.TransactionByHash(context.Background(), tx.Hash()); errors.Is(txErr, ethereum.NotFound) {
+			for _, data := range deposits {
+				log.Printf("Deposit %d (%s) was dropped by the node: %v", data.index, shortPubkey(data.PubKey.String()), err)
+				submitter.AfterSubmit(data, nil, err)
+				notify.Failure(data, tx.Hash().Hex(), err.Error())
+				summary.Failed(data, outcomeFailedNetwork, tx.Hash(), err)
+				metrics.Failed()
+			}
+			return
+		}
+		for _, data := range deposits {
+			log.Printf("Deposit %d (%s) is in flight: %v", data.index, shortPubkey(data.PubKey.String()), err)
+			submitter.AfterSubmit(data, nil, err)
+			notify.Failure(data, tx.Hash().Hex(), err.Error())
+			summary.Failed(data, outcomeInFlight, tx.Hash(), err)
+			metrics.Failed()
+		}
+		log.Printf("Transaction %s was sent and may still be mined: check its receipt before depositing these entries again", tx.Hash().Hex())
+	}
+
+	var pending []pendingDeposit
+	var outstanding []*types.Transaction
+	batchSizes := make(map[common.Hash]int)
+	sent := func(deposits []DepositData, tx *types.Transaction) {
+		signedTxs = append(signedTxs, tx)
+		indices := make([]int, len(deposits))
+		for i, data := range deposits {
+			indices[i] = data.index
+		}
+		signedIndices = append(signedIndices, indices)
+		if cfg.SignOnly {
+			return
+		}
+		crossVerify.Broadcast(context.Background(), tx)
+		txpool.Report(context.Background(), tx)
+		metrics.Submitted(len(deposits), tx)
+		if len(deposits) > 1 {
+			batchSizes[tx.Hash()] = len(deposits)
+		}
+		if cfg.NoWait {
+			for _, data := range deposits {
+				pending = append(pending, pendingDeposit{index: data.index, data: data, tx: tx})
+			}
+			outstanding = append(outstanding, tx)
+			outstanding = waitForCapacity(context.Background(), client, outstanding, cfg.MaxPendingTxs, cfg.Poll())
+			return
+		}
+		receipt, err := submitter.WaitForReceipt(tx)
+		if err != nil {
+			inFlight(deposits, tx, err)
+			report()
+			log.Fatalf("Stopping without the receipt of %s; with --state-file a re-run resumes it instead of sending it again", tx.Hash().Hex())
+		}
+		if cfg.MinConfirmationBlocks > 0 {
+			results := []depositReceipt{{pendingDeposit: pendingDeposit{index: deposits[0].index, data: deposits[0], tx: tx}, receipt: receipt}}
+			err := waitConfirmations(context.Background(), client, results, cfg.MinConfirmationBlocks, cfg.ValidUntilBlock, cfg.Poll())
+			if err == nil {
+				err = results[0].err
+			}
+			if err != nil {
+				report()
+				log.Fatalf("Failed to wait for %d confirmations of %s: %v", cfg.MinConfirmationBlocks, tx.Hash().Hex(), err)
+			}
+			receipt = results[0].receipt
+		}
+		if len(deposits) > 1 {
+			printBatchGas(receipt, len(deposits))
+		}
+		for _, data := range deposits {
+			finish(data, tx, receipt)
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful && !cfg.IgnoreRevert {
+			report()
+			log.Fatalf("Stopping after the revert of %s, pass --ignore-revert to continue past reverts", receipt.TxHash.Hex())
+		}
 	}
 
-	fmt.Printf("Transaction receipt: %s\n", string(receiptJSON))
+	reject := func(data DepositData, err error) {
+		log.Printf("Skipping invalid deposit %d: %v", data.index, err)
+		notify.Failure(data, "", err.Error())
+		summary.Failed(data, outcomeFailedValidation, common.Hash{}, err)
+		metrics.Failed()
+		invalid++
+		if cfg.AbortOnInvalid {
+			report()
+			log.Fatalf("Stopping at invalid deposit %d, remove --abort-on-invalid to skip invalid entries", data.index)
+		}
+	}
+
+	// unsent rejects deposits that were not sent because they are invalid, and
+	// ends the run at deposits that could not be sent, after the summary
+	unsent := func(deposits []DepositData, err error) {
+		var sendErr *sendError
+		if !errors.As(err, &sendErr) {
+			for _, data := range deposits {
+				reject(data, err)
+			}
+			return
+		}
+		if sendErr.network {
+			for _, data := range deposits {
+				notify.Failure(data, "", err.Error())
+				summary.Failed(data, outcomeFailedNetwork, common.Hash{}, err)
+				metrics.Failed()
+			}
+		}
+		report()
+		log.Fatalf("Stopping at deposit %d, nothing was sent for it: %v", deposits[0].index, err)
+	}
+
+	var queue []DepositData
+	flush := func() {
+		if len(queue) == 0 {
+			return
+		}
+		tx, err := submitter.SubmitBatch(batch, queue)
+		if err != nil {
+			unsent(queue, err)
+		} else {
+			sent(queue, tx)
+		}
+		queue = nil
+	}
+
+	for _, data := range depositData {
+		var tx *types.Transaction
+		if state != nil {
+			if record, ok := state.Get(data.DepositDataRoot.String()); ok && isInFlight(record.Status) {
+				tx, err = submitter.Resume(data, record)
+				if err != nil {
+					log.Fatalf("Failed to resume deposit %d: %v", data.index, err)
+				}
+			}
+		}
+		if tx == nil && cfg.BatchSize > 1 {
+			if err := data.checkFieldLengths(); err != nil {
+				reject(data, err)
+				continue
+			}
+			queue = append(queue, data)
+			if len(queue) == cfg.BatchSize {
+				flush()
+			}
+			continue
+		}
+		if tx == nil {
+			tx, err = submitter.Submit(data)
+			if err != nil {
+				unsent([]DepositData{data}, err)
+				continue
+			}
+		}
+		sent([]DepositData{data}, tx)
+	}
+	flush()
+
+	if cfg.SignOnly {
+		submitter.WipeKey()
+		if err := writeBundle(bundleOut, signedTxs, signedIndices); err != nil {
+			log.Fatalf("Failed to write the transaction bundle: %v", err)
+		}
+		fmt.Printf("Signed %d transactions, none was sent\n", len(signedTxs))
+		if invalid > 0 {
+			log.Fatalf("%d deposits were not signed because their deposit data is invalid", invalid)
+		}
+		return
+	}
+
+	if err := submitter.SendBundle(context.Background()); err != nil {
+		report()
+		log.Fatalf("Failed to send the bundle: %v", err)
+	}
+
+	// All signing is done: drop the key material as far as Go allows
+	submitter.WipeKey()
+	cfg.PrivateKey = ""
+	os.Unsetenv("PRIVATE_KEY")
+
+	if cfg.ChunkDelay > 0 {
+		fmt.Printf("--chunk-delay added %s between broadcasts\n", submitter.Paced().Round(time.Millisecond))
+	}
+	if len(pending) > 0 {
+		fmt.Printf("Waiting for %d receipts...\n", len(pending))
+		failed := 0
+		results := collectReceipts(context.Background(), client, pending, cfg.ReceiptWorkers, cfg.Poll())
+		checkReorgs(context.Background(), client, results, cfg.ValidUntilBlock, cfg.Poll())
+		if cfg.MinConfirmationBlocks > 0 {
+			if err := waitConfirmations(context.Background(), client, results, cfg.MinConfirmationBlocks, cfg.ValidUntilBlock, cfg.Poll()); err != nil {
+				report()
+				log.Fatalf("Failed to wait for %d confirmations: %v", cfg.MinConfirmationBlocks, err)
+			}
+		}
+		for _, result := range results {
+			if size := batchSizes[result.tx.Hash()]; size > 1 && result.err == nil {
+				printBatchGas(result.receipt, size)
+				delete(batchSizes, result.tx.Hash())
+			}
+			if result.err != nil {
+				inFlight([]DepositData{result.data}, result.tx, result.err)
+				failed++
+				continue
+			}
+			finish(result.data, result.tx, result.receipt)
+		}
+		if failed > 0 {
+			report()
+			log.Fatalf("%d of %d receipts could not be fetched, these deposits are in flight; with --state-file a re-run resumes them instead of sending them again", failed, len(pending))
+		}
+	}
+
+	report()
+
+	if invalid > 0 {
+		log.Fatalf("%d deposits were not sent because their deposit data is invalid", invalid)
+	}
+	if len(reverted) > 0 && !cfg.IgnoreRevert {
+		log.Fatalf("%d deposits reverted: %s", len(reverted), strings.Join(reverted, ", "))
+	}
+	if len(unverified) > 0 {
+		log.Fatalf("%d deposits mined but failed verification: %s", len(unverified), strings.Join(unverified, ", "))
+	}
+}
+
+// loadEnvFile reads the dotenv file path into the environment, which
+// Config.LoadEnv then picks up. It is the only place that knows about dotenv
+// files: an empty path, --env-file "", skips it and the configuration comes
+// from the environment and flags alone.
+func loadEnvFile(path string) {
+	if path == "" {
+		return
+	}
+	if err := godotenv.Load(path); err != nil {
+		// Without the file the variables may still come from the environment
+		if !errors.Is(err, fs.ErrNotExist) || os.Getenv("RPC_URL") == "" {
+			log.Fatalf("Error loading %s file: %v", path, err)
+		}
+		log.Printf("Warning: %s not found, using the environment", path)
+	}
+}
+
+// isNonceTooLow matches core.ErrNonceTooLow as relayed by the node over JSON-RPC.
+func isNonceTooLow(err error) bool {
+	return strings.Contains(err.Error(), "nonce too low")
+}
+
+func isAlreadyKnown(err error) bool {
+	return strings.Contains(err.Error(), "already known")
 }
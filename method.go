@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const defaultDepositMethod = "deposit"
+
+// depositCall is the contract method receiving the deposits. It takes the
+// pubkey, withdrawal credentials, signature and deposit data root of the
+// canonical deposit function, followed by any extra arguments of a wrapper
+// contract.
+type depositCall struct {
+	method abi.Method
+	extra  []interface{}
+}
+
+// newDepositCall looks method up in contractABI, checks that it starts with
+// the canonical deposit parameters and parses args for the remaining ones.
+func newDepositCall(contractABI abi.ABI, name string, args []string) (depositCall, error) {
+	method, ok := contractABI.Methods[name]
+	if !ok {
+		return depositCall{}, fmt.Errorf("method %q is not in the ABI", name)
+	}
+
+	canonical := []string{"bytes", "bytes", "bytes", "bytes32"}
+	if len(method.Inputs) < len(canonical) {
+		return depositCall{}, fmt.Errorf("method %s must start with (%s)", method.Sig, strings.Join(canonical, ","))
+	}
+	for i, want := range canonical {
+		if got := method.Inputs[i].Type.String(); got != want {
+			return depositCall{}, fmt.Errorf("method %s must start with (%s), parameter %d is %s", method.Sig, strings.Join(canonical, ","), i, got)
+		}
+	}
+
+	params := method.Inputs[len(canonical):]
+	if len(args) != len(params) {
+		return depositCall{}, fmt.Errorf("method %s takes %d extra arguments, %d given with --deposit-arg", method.Sig, len(params), len(args))
+	}
+	call := depositCall{method: method}
+	for i, param := range params {
+		value, err := parseABIValue(param.Type, args[i])
+		if err != nil {
+			return depositCall{}, fmt.Errorf("argument %s of %s: %w", param.Name, method.Sig, err)
+		}
+		call.extra = append(call.extra, value)
+	}
+	return call, nil
+}
+
+// Pack encodes the call data of one deposit.
+func (c depositCall) Pack(pubkey, withdrawalCredentials, signature []byte, depositDataRoot [32]byte) ([]byte, error) {
+	args := append([]interface{}{pubkey, withdrawalCredentials, signature, depositDataRoot}, c.extra...)
+	packed, err := c.method.Inputs.Pack(args...)
+	if err != nil {
+		return nil, packError(c.method, args, err)
+	}
+	return append(c.method.ID, packed...), nil
+}
+
+// packError explains why args could not be packed for method by packing
+// each argument against its declared input type on its own, which names the
+// first one that does not fit. err, the error of packing them all, is kept
+// if every argument fits alone.
+func packError(method abi.Method, args []interface{}, err error) error {
+	for i, input := range method.Inputs {
+		if i >= len(args) {
+			break
+		}
+		if _, argErr := (abi.Arguments{input}).Pack(args[i]); argErr != nil {
+			name := input.Name
+			if name == "" {
+				name = "#" + strconv.Itoa(i)
+			}
+			return fmt.Errorf("%s: argument %s is declared %s, got %T: %w", method.Sig, name, input.Type, args[i], argErr)
+		}
+	}
+	return fmt.Errorf("%s: %w", method.Sig, err)
+}
+
+// calldataHashes prints the keccak256 of the calldata of every deposit.
+func calldataHashes(call depositCall, deposits []DepositData) error {
+	fmt.Printf("Method %s, selector %s\n", call.method.Sig, hexutil.Encode(call.method.ID))
+	for _, data := range deposits {
+		if err := data.checkFieldLengths(); err != nil {
+			return fmt.Errorf("deposit %d: %w", data.index, err)
+		}
+		calldata, err := call.Pack(data.PubKey, data.WithdrawalCredentials, data.Signature, data.root())
+		if err != nil {
+			return fmt.Errorf("deposit %d: failed to pack arguments: %w", data.index, err)
+		}
+		fmt.Printf("%d  %s  %s\n", data.index, shortPubkey(data.PubKey.String()), crypto.Keccak256Hash(calldata).Hex())
+	}
+	return nil
+}
+
+// parseABIValue converts s into the Go value that the abi package packs as t.
+func parseABIValue(t abi.Type, s string) (interface{}, error) {
+	switch t.T {
+	case abi.AddressTy:
+		if !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("invalid address %q", s)
+		}
+		return common.HexToAddress(s), nil
+	case abi.BoolTy:
+		return strconv.ParseBool(s)
+	case abi.StringTy:
+		return s, nil
+	case abi.BytesTy:
+		return hexutil.Decode(s)
+	case abi.FixedBytesTy:
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != t.Size {
+			return nil, fmt.Errorf("%s needs %d bytes, got %d", t, t.Size, len(b))
+		}
+		v := reflect.New(t.GetType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v.Interface(), nil
+	case abi.UintTy, abi.IntTy:
+		n, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+		if t.Size > 64 {
+			return n, nil
+		}
+		v := reflect.New(t.GetType()).Elem()
+		if t.T == abi.UintTy {
+			if n.Sign() < 0 || n.BitLen() > t.Size {
+				return nil, fmt.Errorf("%s out of range for %s", s, t)
+			}
+			v.SetUint(n.Uint64())
+		} else {
+			if !n.IsInt64() || v.OverflowInt(n.Int64()) {
+				return nil, fmt.Errorf("%s out of range for %s", s, t)
+			}
+			v.SetInt(n.Int64())
+		}
+		return v.Interface(), nil
+	default:
+		return nil, fmt.Errorf("unsupported parameter type %s", t)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// wrongABI declares pubkey as bytes32, and a wrapper argument as uint256.
+const wrongABI = `[
+	{"type": "function", "name": "deposit", "stateMutability": "payable", "outputs": [], "inputs": [
+		{"name": "pubkey", "type": "bytes32"},
+		{"name": "withdrawal_credentials", "type": "bytes"},
+		{"name": "signature", "type": "bytes"},
+		{"name": "deposit_data_root", "type": "bytes32"}
+	]},
+	{"type": "function", "name": "depositFor", "stateMutability": "payable", "outputs": [], "inputs": [
+		{"name": "pubkey", "type": "bytes"},
+		{"name": "withdrawal_credentials", "type": "bytes"},
+		{"name": "signature", "type": "bytes"},
+		{"name": "deposit_data_root", "type": "bytes32"},
+		{"name": "referrer", "type": "uint256"}
+	]},
+	{"type": "function", "name": "batchDeposit", "stateMutability": "payable", "outputs": [], "inputs": [
+		{"name": "pubkeys", "type": "bytes[]"},
+		{"name": "withdrawal_credentials", "type": "bytes[]"},
+		{"name": "signatures", "type": "bytes32[]"},
+		{"name": "deposit_data_roots", "type": "bytes32[]"}
+	]}
+]`
+
+func TestPackError(t *testing.T) {
+	contractABI, err := abi.JSON(strings.NewReader(wrongABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := testDepositData(t, 0x11, 32_000_000_000)
+
+	// The method checks of newDepositCall and newBatchCall are bypassed to
+	// pack against the wrong types
+	node := newFakeNode(t, holeskyChainID)
+	submitter := node.submitter(t, nil)
+	submitter.call = depositCall{method: contractABI.Methods["deposit"]}
+	_, err = submitter.Submit(data)
+	want := "deposit(bytes32,bytes,bytes,bytes32): argument pubkey is declared bytes32, got []uint8"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("deposit: error %v, want %q", err, want)
+	}
+
+	call := depositCall{method: contractABI.Methods["depositFor"], extra: []interface{}{"alice"}}
+	_, err = call.Pack(data.PubKey, data.WithdrawalCredentials, data.Signature, data.root())
+	want = "depositFor(bytes,bytes,bytes,bytes32,uint256): argument referrer is declared uint256, got string"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("wrapper: error %v, want %q", err, want)
+	}
+	// With the right type it packs
+	call.extra = []interface{}{big.NewInt(7)}
+	if _, err := call.Pack(data.PubKey, data.WithdrawalCredentials, data.Signature, data.root()); err != nil {
+		t.Errorf("wrapper with a uint256: %v", err)
+	}
+
+	_, err = submitter.SubmitBatch(batchCall{method: contractABI.Methods["batchDeposit"]}, []DepositData{data})
+	want = "batchDeposit(bytes[],bytes[],bytes32[],bytes32[]): argument signatures is declared bytes32[], got [][]uint8"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("batch: error %v, want %q", err, want)
+	}
+
+	if sent := node.Sent(); len(sent) != 0 {
+		t.Errorf("%d transactions sent", len(sent))
+	}
+}
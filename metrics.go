@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// runMetrics counts the progress of a run for --metrics-addr. A nil
+// *runMetrics ignores every update.
+type runMetrics struct {
+	mu        sync.Mutex
+	submitted uint64
+	failed    uint64
+	confirmed uint64
+	gasSpent  *big.Int
+	nonce     uint64
+	// counted holds the transactions whose gas is in gasSpent, a batch
+	// transaction is reported once per deposit.
+	counted map[common.Hash]bool
+}
+
+func newRunMetrics() *runMetrics {
+	return &runMetrics{gasSpent: new(big.Int), counted: make(map[common.Hash]bool)}
+}
+
+// Submitted counts the deposits sent in tx.
+func (m *runMetrics) Submitted(deposits int, tx *types.Transaction) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.submitted += uint64(deposits)
+	m.nonce = tx.Nonce()
+}
+
+// Failed counts a deposit that failed validation, reverted or was lost.
+func (m *runMetrics) Failed() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed++
+}
+
+// Mined counts a deposit mined in receipt with the given status and the
+// gas of its transaction.
+func (m *runMetrics) Mined(status string, receipt *types.Receipt) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch status {
+	case statusConfirmed, statusVerified:
+		m.confirmed++
+	case statusReverted, statusUnverified:
+		m.failed++
+	}
+	if !m.counted[receipt.TxHash] && receipt.EffectiveGasPrice != nil {
+		m.counted[receipt.TxHash] = true
+		m.gasSpent.Add(m.gasSpent, new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice))
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *runMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, metric := range []struct {
+		name, kind, help string
+		value            any
+	}{
+		{"deposits_submitted_total", "counter", "Deposits sent to the node.", m.submitted},
+		{"deposits_failed_total", "counter", "Deposits that failed validation, reverted or whose receipt was lost.", m.failed},
+		{"deposits_confirmed_total", "counter", "Deposits mined successfully.", m.confirmed},
+		{"gas_spent_wei", "counter", "Fees paid by the mined deposit transactions in wei.", m.gasSpent},
+		{"current_nonce", "gauge", "Nonce of the last transaction sent.", m.nonce},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", metric.name, metric.help, metric.name, metric.kind, metric.name, metric.value)
+	}
+}
+
+// serveMetrics serves m on addr until the returned function is called.
+func serveMetrics(addr string, m *runMetrics) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
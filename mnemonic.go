@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// defaultHDPath is the BIP-44 path of the first Ethereum account, as used by
+// most wallets.
+const defaultHDPath = "m/44'/60'/0'/0/0"
+
+// hardenedOffset marks a hardened BIP-32 child index.
+const hardenedOffset = 1 << 31
+
+// parseHDPath parses a derivation path such as m/44'/60'/0'/0/0 into child
+// indices, hardened ones with hardenedOffset added.
+func parseHDPath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) < 2 || parts[0] != "m" {
+		return nil, fmt.Errorf("invalid HD path %q, expected e.g. %s", path, defaultHDPath)
+	}
+	indices := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		part = strings.TrimRight(part, "'h")
+		n, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HD path %q: bad index %q", path, part)
+		}
+		index := uint32(n)
+		if hardened {
+			index += hardenedOffset
+		}
+		indices = append(indices, index)
+	}
+	return indices, nil
+}
+
+// mnemonicSeed is the BIP-39 seed of mnemonic and passphrase. Only the
+// English word list is in use, whose words need no Unicode normalization;
+// the words are not checked against it.
+func mnemonicSeed(mnemonic, passphrase string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return nil, fmt.Errorf("mnemonic has %d words, expected 12, 15, 18, 21 or 24", len(words))
+	}
+	for _, word := range words {
+		for _, r := range word {
+			if r < 'a' || r > 'z' {
+				return nil, errors.New("mnemonic words must be lowercase English words")
+			}
+		}
+	}
+	return pbkdf2.Key([]byte(strings.Join(words, " ")), []byte("mnemonic"+passphrase), 2048, 64, sha512.New), nil
+}
+
+// deriveKey derives the BIP-32 private key of path from seed.
+func deriveKey(seed []byte, path []uint32) (*ecdsa.PrivateKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	defer clear(sum)
+	key, chainCode := new(big.Int).SetBytes(sum[:32]), sum[32:]
+
+	n := crypto.S256().Params().N
+	if key.Sign() == 0 || key.Cmp(n) >= 0 {
+		return nil, errors.New("invalid master key, use another mnemonic")
+	}
+	for _, index := range path {
+		var data []byte
+		if index >= hardenedOffset {
+			data = append([]byte{0}, common.LeftPadBytes(key.Bytes(), 32)...)
+		} else {
+			parent, err := crypto.ToECDSA(common.LeftPadBytes(key.Bytes(), 32))
+			if err != nil {
+				return nil, err
+			}
+			data = crypto.CompressPubkey(&parent.PublicKey)
+		}
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		mac := hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		clear(data)
+		child := mac.Sum(nil)
+		tweak := new(big.Int).SetBytes(child[:32])
+		if tweak.Cmp(n) >= 0 {
+			return nil, fmt.Errorf("invalid child key at index %d, use the next index", index)
+		}
+		key.Add(key, tweak).Mod(key, n)
+		if key.Sign() == 0 {
+			return nil, fmt.Errorf("invalid child key at index %d, use the next index", index)
+		}
+		chainCode = child[32:]
+	}
+
+	b := common.LeftPadBytes(key.Bytes(), 32)
+	defer clear(b)
+	return crypto.ToECDSA(b)
+}
+
+// mnemonicKey derives the signing key of hdPath from the BIP-39 mnemonic in
+// path, with the optional passphrase of MNEMONIC_PASSPHRASE.
+func mnemonicKey(path, hdPath string) (*ecdsa.PrivateKey, error) {
+	indices, err := parseHDPath(hdPath)
+	if err != nil {
+		return nil, err
+	}
+	mnemonic, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(mnemonic)
+	seed, err := mnemonicSeed(string(mnemonic), os.Getenv("MNEMONIC_PASSPHRASE"))
+	if err != nil {
+		return nil, err
+	}
+	defer clear(seed)
+	return deriveKey(seed, indices)
+}
+
+// signingKey returns the key deposits are signed with: PRIVATE_KEY or the
+// key derived from --mnemonic-file. With --expected-from the address of the
+// key has to match.
+func signingKey(cfg Config) (*ecdsa.PrivateKey, error) {
+	var key *ecdsa.PrivateKey
+	var err error
+	if cfg.MnemonicFile != "" {
+		key, err = mnemonicKey(cfg.MnemonicFile, cfg.HDPath)
+	} else {
+		key, err = parsePrivateKey(cfg.PrivateKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ExpectedFrom != "" {
+		if from := crypto.PubkeyToAddress(key.PublicKey); from != common.HexToAddress(cfg.ExpectedFrom) {
+			wipePrivateKey(key)
+			return nil, fmt.Errorf("key is for %s, --expected-from is %s", from.Hex(), cfg.ExpectedFrom)
+		}
+	}
+	return key, nil
+}
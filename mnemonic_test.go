@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testMnemonic is the mnemonic of the Hardhat and Anvil development accounts.
+const testMnemonic = "test test test test test test test test test test test junk"
+
+func TestMnemonicKey(t *testing.T) {
+	t.Setenv("MNEMONIC_PASSPHRASE", "")
+	path := filepath.Join(t.TempDir(), "mnemonic.txt")
+	if err := os.WriteFile(path, []byte(testMnemonic+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		hdPath  string
+		address string
+	}{
+		{hdPath: defaultHDPath, address: "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"},
+		{hdPath: "m/44'/60'/0'/0/1", address: "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"},
+		{hdPath: "m/44h/60h/0h/0/1", address: "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"},
+	}
+	for _, tt := range tests {
+		key, err := mnemonicKey(path, tt.hdPath)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.hdPath, err)
+		}
+		if got := crypto.PubkeyToAddress(key.PublicKey).Hex(); got != tt.address {
+			t.Errorf("%s derived %s, want %s", tt.hdPath, got, tt.address)
+		}
+	}
+
+	// The first account is the test account of PRIVATE_KEY
+	key, _ := mnemonicKey(path, defaultHDPath)
+	if got := hex.EncodeToString(crypto.FromECDSA(key)); got != testPrivateKey {
+		t.Errorf("derived key %s, want the test private key", got)
+	}
+
+	// A passphrase derives other accounts
+	t.Setenv("MNEMONIC_PASSPHRASE", "secret")
+	key, err := mnemonicKey(path, defaultHDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := crypto.PubkeyToAddress(key.PublicKey).Hex(); got == tests[0].address {
+		t.Error("the passphrase was ignored")
+	}
+}
+
+func TestSigningKeyExpectedFrom(t *testing.T) {
+	t.Setenv("MNEMONIC_PASSPHRASE", "")
+	path := filepath.Join(t.TempDir(), "mnemonic.txt")
+	if err := os.WriteFile(path, []byte(testMnemonic), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.MnemonicFile, cfg.HDPath = path, "m/44'/60'/0'/0/1"
+
+	cfg.ExpectedFrom = "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"
+	if _, err := signingKey(cfg); err != nil {
+		t.Errorf("matching --expected-from: %v", err)
+	}
+	cfg.ExpectedFrom = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	want := "key is for 0x70997970C51812dc3A010C7d01b50e0d17dc79C8, --expected-from is 0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	if _, err := signingKey(cfg); err == nil || err.Error() != want {
+		t.Errorf("error %v, want %q", err, want)
+	}
+}
+
+func TestMnemonicErrors(t *testing.T) {
+	if _, err := mnemonicSeed("test test junk", ""); err == nil || !strings.Contains(err.Error(), "mnemonic has 3 words") {
+		t.Errorf("error %v for 3 words", err)
+	}
+	if _, err := mnemonicSeed(strings.Replace(testMnemonic, "junk", "Junk", 1), ""); err == nil {
+		t.Error("an uppercase word was accepted")
+	}
+	for _, path := range []string{"44'/60'/0'/0/0", "m/44'/60'/x/0/0", "m"} {
+		if _, err := parseHDPath(path); err == nil {
+			t.Errorf("HD path %q was accepted", path)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// depositProfiles are the amount semantics a network profile file can name.
+var depositProfiles = map[string]depositProfile{
+	ethereumProfile{}.Name(): ethereumProfile{},
+}
+
+// networkProfileFile is the JSON of --deposit-profile, describing a network
+// that is not in the built-in table or replacing one that is.
+type networkProfileFile struct {
+	ChainID               uint64 `json:"chain_id"`
+	Name                  string `json:"name"`
+	DepositContract       string `json:"deposit_contract"`
+	GenesisForkVersion    string `json:"genesis_fork_version"`
+	GenesisValidatorsRoot string `json:"genesis_validators_root"`
+	ExplorerURL           string `json:"explorer_url,omitempty"`
+	BeaconExplorerURL     string `json:"beacon_explorer_url,omitempty"`
+	// AmountSemantics names the depositProfile, ethereum by default.
+	AmountSemantics string `json:"amount_semantics,omitempty"`
+	// WarnTipGwei is the default of --warn-tip-gwei on the network.
+	WarnTipGwei json.Number `json:"warn_tip_gwei,omitempty"`
+}
+
+// loadNetworkProfile reads the network profile in path and returns its
+// chain ID and network. Unknown fields are rejected, a misspelled optional
+// field would otherwise silently fall back to its default.
+func loadNetworkProfile(path string) (uint64, network, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, network{}, err
+	}
+	var p networkProfileFile
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return 0, network{}, fmt.Errorf("invalid network profile: %w", err)
+	}
+
+	var missing []string
+	for _, f := range []struct {
+		name  string
+		unset bool
+	}{
+		{"chain_id", p.ChainID == 0},
+		{"name", p.Name == ""},
+		{"deposit_contract", p.DepositContract == ""},
+		{"genesis_fork_version", p.GenesisForkVersion == ""},
+		{"genesis_validators_root", p.GenesisValidatorsRoot == ""},
+	} {
+		if f.unset {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return 0, network{}, fmt.Errorf("network profile is missing %s", strings.Join(missing, ", "))
+	}
+
+	n := network{
+		Name:              p.Name,
+		ExplorerURL:       strings.TrimRight(p.ExplorerURL, "/"),
+		BeaconExplorerURL: strings.TrimRight(p.BeaconExplorerURL, "/"),
+	}
+	if !common.IsHexAddress(p.DepositContract) {
+		return 0, network{}, fmt.Errorf("deposit_contract %q is not an address", p.DepositContract)
+	}
+	n.DepositContract = common.HexToAddress(p.DepositContract)
+	if n.GenesisForkVersion, err = parseForkVersion(p.GenesisForkVersion); err != nil {
+		return 0, network{}, fmt.Errorf("genesis_fork_version: %w", err)
+	}
+	root, err := hexField("genesis_validators_root", p.GenesisValidatorsRoot, common.HashLength)
+	if err != nil {
+		return 0, network{}, err
+	}
+	n.GenesisValidatorsRoot = common.BytesToHash(root)
+	if n.GenesisValidatorsRoot == (common.Hash{}) {
+		return 0, network{}, errors.New("genesis_validators_root must not be zero")
+	}
+
+	if p.AmountSemantics != "" {
+		profile, ok := depositProfiles[p.AmountSemantics]
+		if !ok {
+			names := make([]string, 0, len(depositProfiles))
+			for name := range depositProfiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return 0, network{}, fmt.Errorf("unknown amount_semantics %q, expected one of %s", p.AmountSemantics, strings.Join(names, ", "))
+		}
+		n.Profile = profile
+	}
+	if p.WarnTipGwei != "" {
+		if n.WarnTip, err = parseGwei(p.WarnTipGwei.String()); err != nil {
+			return 0, network{}, fmt.Errorf("warn_tip_gwei: %w", err)
+		}
+	}
+	return p.ChainID, n, nil
+}
+
+// registerNetworkProfile merges the network profile in path into the
+// network table, replacing a built-in network of the same chain ID.
+func registerNetworkProfile(path string) error {
+	chainID, n, err := loadNetworkProfile(path)
+	if err != nil {
+		return err
+	}
+	if old, ok := networks[chainID]; ok {
+		fmt.Printf("Network profile %s replaces %s (chain ID %d)\n", n.Name, old.Name, chainID)
+	} else {
+		fmt.Printf("Network profile %s (chain ID %d)\n", n.Name, chainID)
+	}
+	networks[chainID] = n
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// network describes a chain the tool knows about.
+type network struct {
+	Name string
+	// DepositContract is the address of the beacon deposit contract.
+	DepositContract common.Address
+	// GenesisForkVersion is the fork version deposits are signed with.
+	GenesisForkVersion [4]byte
+	// GenesisValidatorsRoot identifies the beacon chain of the network.
+	GenesisValidatorsRoot common.Hash
+	// ExplorerURL is the execution layer explorer, BeaconExplorerURL the consensus layer one.
+	ExplorerURL       string
+	BeaconExplorerURL string
+	// Profile defaults to ethereumProfile when nil.
+	Profile depositProfile
+	// WarnTip is the default of --warn-tip-gwei in wei, defaultWarnTip
+	// when nil.
+	WarnTip *big.Int
+}
+
+// defaultWarnTip is the tip above which a suggested tip is warned about on
+// mainnet and networks without their own threshold. Testnet tips spike far
+// higher without costing real ETH.
+var (
+	defaultWarnTip = big.NewInt(10_000_000_000)
+	testnetWarnTip = big.NewInt(100_000_000_000)
+)
+
+// networks is keyed by chain ID.
+var networks = map[uint64]network{
+	1: {
+		Name:                  "mainnet",
+		DepositContract:       common.HexToAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa"),
+		GenesisForkVersion:    [4]byte{0x00, 0x00, 0x00, 0x00},
+		GenesisValidatorsRoot: common.HexToHash("0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"),
+		ExplorerURL:           "https://etherscan.io",
+		BeaconExplorerURL:     "https://beaconcha.in",
+	},
+	11155111: {
+		Name:                  "sepolia",
+		DepositContract:       common.HexToAddress("0x7f02C3E3c98b133055B8B348B2Ac625669Ed295D"),
+		GenesisForkVersion:    [4]byte{0x90, 0x00, 0x00, 0x69},
+		GenesisValidatorsRoot: common.HexToHash("0xd8ea171f3c94aea21ebc42a1ed61052acf3f9209c00e4efbaaddac09ed9b8078"),
+		ExplorerURL:           "https://sepolia.etherscan.io",
+		BeaconExplorerURL:     "https://sepolia.beaconcha.in",
+		WarnTip:               testnetWarnTip,
+	},
+	17000: {
+		Name:                  "holesky",
+		DepositContract:       common.HexToAddress("0x4242424242424242424242424242424242424242"),
+		GenesisForkVersion:    [4]byte{0x01, 0x01, 0x70, 0x00},
+		GenesisValidatorsRoot: common.HexToHash("0x9143aa7c615a7f7115e2b6aac319c03529df8242ae705fba9df39b79c59fa8b1"),
+		ExplorerURL:           "https://holesky.etherscan.io",
+		BeaconExplorerURL:     "https://holesky.beaconcha.in",
+		WarnTip:               testnetWarnTip,
+	},
+	560048: {
+		Name:                  "hoodi",
+		DepositContract:       common.HexToAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa"),
+		GenesisForkVersion:    [4]byte{0x10, 0x00, 0x09, 0x10},
+		GenesisValidatorsRoot: common.HexToHash("0x212f13fc4df078b6cb7db228f1c8307566dcecf900867401a92023d7ba99cb5f"),
+		ExplorerURL:           "https://hoodi.etherscan.io",
+		BeaconExplorerURL:     "https://hoodi.beaconcha.in",
+		WarnTip:               testnetWarnTip,
+	},
+}
+
+// resolveChainID returns the chain ID transactions are signed for: the one
+// reported by the node, which must match the --chain-id override if set.
+func resolveChainID(ctx context.Context, client *ethclient.Client, override uint64) (*big.Int, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	if override != 0 && (!chainID.IsUint64() || chainID.Uint64() != override) {
+		return nil, fmt.Errorf("the node at RPC_URL is on chain %s, not on --chain-id %d; refusing to sign for the wrong chain", chainID, override)
+	}
+	return chainID, nil
+}
+
+func networkByChainID(chainID *big.Int) (network, bool) {
+	if !chainID.IsUint64() {
+		return network{}, false
+	}
+	n, ok := networks[chainID.Uint64()]
+	return n, ok
+}
+
+func (n network) DepositProfile() depositProfile {
+	if n.Profile == nil {
+		return ethereumProfile{}
+	}
+	return n.Profile
+}
+
+func (n network) WarnTipThreshold() *big.Int {
+	if n.WarnTip == nil {
+		return defaultWarnTip
+	}
+	return n.WarnTip
+}
+
+// genesisRootMismatches returns the deposits whose genesis_validators_root
+// is not the one of the network, i.e. that were generated for another one.
+func genesisRootMismatches(deposits []DepositData, n network) []DepositData {
+	var mismatched []DepositData
+	for _, data := range deposits {
+		if data.GenesisValidatorsRoot != "" && common.HexToHash(data.GenesisValidatorsRoot) != n.GenesisValidatorsRoot {
+			mismatched = append(mismatched, data)
+		}
+	}
+	return mismatched
+}
+
+// chainMismatch is an entry whose network_name or fork_version belongs to a
+// known network on another chain than the node's.
+type chainMismatch struct {
+	data    DepositData
+	field   string
+	value   string
+	chainID uint64
+	network network
+}
+
+// chainIDMismatches returns the entries meant for another chain than
+// chainID according to their network_name or, without one, their
+// fork_version. Names and fork versions of unknown networks imply nothing.
+func chainIDMismatches(deposits []DepositData, chainID *big.Int) []chainMismatch {
+	var mismatched []chainMismatch
+	for _, data := range deposits {
+		for id, n := range networks {
+			var field, value string
+			if data.NetworkName != "" {
+				if !strings.EqualFold(data.NetworkName, n.Name) {
+					continue
+				}
+				field, value = "network_name", data.NetworkName
+			} else if version, err := parseForkVersion(data.ForkVersion); data.ForkVersion != "" && err == nil && version == n.GenesisForkVersion {
+				field, value = "fork_version", data.ForkVersion
+			} else {
+				continue
+			}
+			if !chainID.IsUint64() || chainID.Uint64() != id {
+				mismatched = append(mismatched, chainMismatch{data: data, field: field, value: value, chainID: id, network: n})
+			}
+			break
+		}
+	}
+	return mismatched
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// NonceManager hands out the nonces of one account. The base nonce is
+// fetched from the configured nonce source once, later nonces follow it
+// locally, so that transactions sent without waiting for their receipts get
+// consecutive nonces; the latest nonce does not move until the previous
+// transaction is mined. It is safe for concurrent use.
+type NonceManager struct {
+	client *ethclient.Client
+	from   common.Address
+	source string
+
+	mu     sync.Mutex
+	synced bool
+	next   uint64
+	// floor is the nonce after those recorded by Used before the sync.
+	floor uint64
+}
+
+func NewNonceManager(client *ethclient.Client, from common.Address, source string) *NonceManager {
+	return &NonceManager{client: client, from: from, source: source}
+}
+
+// Peek returns the nonce the next transaction will get without taking it.
+func (m *NonceManager) Peek(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.sync(ctx); err != nil {
+		return 0, err
+	}
+	return m.next, nil
+}
+
+// Next takes the nonce for the next transaction.
+func (m *NonceManager) Next(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.sync(ctx); err != nil {
+		return 0, err
+	}
+	nonce := m.next
+	m.next++
+	return nonce, nil
+}
+
+// Used records a nonce taken outside of Next, such as the one of a resumed
+// transaction, so that it is not handed out again.
+func (m *NonceManager) Used(nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.synced {
+		m.floor = max(m.floor, nonce+1)
+	} else if nonce >= m.next {
+		m.next = nonce + 1
+	}
+}
+
+// Refresh drops the local nonce for the pending nonce of the node, after a
+// send failed because another transaction used the nonce. The pending nonce
+// is the first one the node would accept.
+func (m *NonceManager) Refresh(ctx context.Context) error {
+	nonce, err := m.client.PendingNonceAt(ctx, m.from)
+	if err != nil {
+		return fmt.Errorf("failed to get nonce: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next, m.synced = nonce, true
+	return nil
+}
+
+// sync fetches the base nonce on first use. The caller holds m.mu.
+func (m *NonceManager) sync(ctx context.Context) error {
+	if m.synced {
+		return nil
+	}
+	var nonce uint64
+	var err error
+	if m.source == nonceSourceLatest {
+		nonce, err = m.client.NonceAt(ctx, m.from, nil)
+	} else {
+		nonce, err = m.client.PendingNonceAt(ctx, m.from)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get nonce: %w", err)
+	}
+	m.next, m.synced = max(nonce, m.floor), true
+	return nil
+}
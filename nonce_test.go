@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestNonceManager(t *testing.T) {
+	ctx := context.Background()
+	from := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+
+	t.Run("sequential", func(t *testing.T) {
+		node := newFakeNode(t, holeskyChainID)
+		node.nonce = 5
+		nonces := NewNonceManager(node.client(t), from, nonceSourcePending)
+
+		const n = 20
+		got := make([]bool, n)
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				nonce, err := nonces.Next(ctx)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if nonce < 5 || nonce >= 5+n || got[nonce-5] {
+					t.Errorf("nonce %d handed out twice or out of range", nonce)
+					return
+				}
+				got[nonce-5] = true
+			}()
+		}
+		wg.Wait()
+		if next, _ := nonces.Peek(ctx); next != 5+n {
+			t.Errorf("next nonce %d, want %d", next, 5+n)
+		}
+		if calls := node.Calls("eth_getTransactionCount"); calls != 1 {
+			t.Errorf("%d nonce requests, the base nonce is fetched once", calls)
+		}
+	})
+
+	t.Run("refresh after a gap", func(t *testing.T) {
+		node := newFakeNode(t, holeskyChainID)
+		nonces := NewNonceManager(node.client(t), from, nonceSourcePending)
+		for want := uint64(0); want < 3; want++ {
+			if nonce, err := nonces.Next(ctx); err != nil || nonce != want {
+				t.Fatalf("nonce %d (%v), want %d", nonce, err, want)
+			}
+		}
+		// Another wallet of the account sent transactions meanwhile
+		node.nonce = 9
+		if nonce, _ := nonces.Peek(ctx); nonce != 3 {
+			t.Errorf("nonce %d before the refresh, want the local 3", nonce)
+		}
+		if err := nonces.Refresh(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if nonce, err := nonces.Next(ctx); err != nil || nonce != 9 {
+			t.Errorf("nonce %d (%v) after the refresh, want 9", nonce, err)
+		}
+	})
+
+	t.Run("used before the first sync", func(t *testing.T) {
+		node := newFakeNode(t, holeskyChainID)
+		node.nonce = 2
+		nonces := NewNonceManager(node.client(t), from, nonceSourceLatest)
+		// A resumed transaction holds nonce 4, not mined yet
+		nonces.Used(4)
+		if nonce, err := nonces.Next(ctx); err != nil || nonce != 5 {
+			t.Errorf("nonce %d (%v), want 5 after the resumed one", nonce, err)
+		}
+		nonces.Used(3)
+		if nonce, _ := nonces.Next(ctx); nonce != 6 {
+			t.Errorf("nonce %d, an older used nonce must not move it back", nonce)
+		}
+	})
+}
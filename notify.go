@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+)
+
+// batchSummary is posted to --notify-url when a batch finishes. Text carries
+// a human-readable summary so that Slack-compatible webhooks display it.
+type batchSummary struct {
+	Event           string  `json:"event"` // always "batch_completed"
+	Text            string  `json:"text"`
+	ChainID         uint64  `json:"chain_id"`
+	Total           int     `json:"total"`
+	Succeeded       int     `json:"succeeded"`
+	Failed          int     `json:"failed"`
+	TotalETH        string  `json:"total_eth"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// failureNotice is posted to --notify-url for every failed deposit when
+// --notify-failures is set.
+type failureNotice struct {
+	Event  string `json:"event"` // always "deposit_failed"
+	Text   string `json:"text"`
+	Index  int    `json:"index"`
+	PubKey string `json:"pubkey"`
+	TxHash string `json:"tx_hash,omitempty"`
+	Error  string `json:"error"`
+}
+
+type notifier struct {
+	hook     *webhook
+	failures bool
+}
+
+// Failure posts a failureNotice if per-failure notifications are enabled.
+// Notification errors never abort the run.
+func (n *notifier) Failure(data DepositData, txHash string, cause string) {
+	if n == nil || !n.failures {
+		return
+	}
+	notice := failureNotice{
+		Event:  "deposit_failed",
+		Text:   fmt.Sprintf("go-deposit: deposit %d for %s failed: %s", data.index, shortPubkey(data.PubKey.String()), cause),
+		Index:  data.index,
+		PubKey: data.PubKey.String(),
+		TxHash: txHash,
+		Error:  cause,
+	}
+	if err := n.hook.post(notice); err != nil {
+		log.Printf("Warning: failed to send failure notification: %v", err)
+	}
+}
+
+func (n *notifier) Completed(run *runSummary, chainID *big.Int) {
+	if n == nil {
+		return
+	}
+	total, succeeded, failed, gwei := run.Counts()
+	summary := batchSummary{
+		Event:           "batch_completed",
+		ChainID:         chainID.Uint64(),
+		Total:           total,
+		Succeeded:       succeeded,
+		Failed:          failed,
+		TotalETH:        formatGweiAsETH(gwei),
+		DurationSeconds: time.Since(run.started).Seconds(),
+	}
+	summary.Text = fmt.Sprintf("go-deposit: batch on chain %d completed, %d/%d deposits succeeded, %d failed, %s ETH deposited in %s",
+		summary.ChainID, summary.Succeeded, summary.Total, summary.Failed, summary.TotalETH, time.Since(run.started).Round(time.Second))
+	if err := n.hook.post(summary); err != nil {
+		log.Printf("Warning: failed to send completion notification: %v", err)
+	}
+}
+
+func shortPubkey(pubkey string) string {
+	pubkey = normalizePubkey(pubkey)
+	if len(pubkey) <= 12 {
+		return "0x" + pubkey
+	}
+	return "0x" + pubkey[:8] + "…" + pubkey[len(pubkey)-4:]
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runManifest describes a run in its --output-dir, so that the artifacts
+// can be traced back to the input and the configuration used.
+type runManifest struct {
+	Started       string            `json:"started"`
+	Subcommand    string            `json:"subcommand"`
+	DepositFile   string            `json:"deposit_file,omitempty"`
+	DepositSHA256 string            `json:"deposit_file_sha256,omitempty"`
+	Flags         map[string]string `json:"flags"`
+}
+
+// prepareOutputDir creates a timestamped directory for this run below
+// c.OutputDir and moves every relative artifact path of c into it. The log
+// and the summary are always written there. The state file is left alone,
+// it has to survive the run to be resumed.
+func (c *Config) prepareOutputDir(now time.Time) (string, error) {
+	dir := filepath.Join(c.OutputDir, now.UTC().Format("20060102-150405"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	if c.LogFile == "" {
+		c.LogFile = "run.log"
+	}
+	if c.SummaryFile == "" {
+		c.SummaryFile = "summary.json"
+	}
+	for _, path := range []*string{&c.LogFile, &c.SummaryFile, &c.IndexMap, &c.DumpSigningData, &c.ReceiptOutputDir} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+	return dir, nil
+}
+
+// writeManifest records the subcommand, the deposit file with its hash and
+// every flag value in dir/manifest.json. Secrets only come from the
+// environment and are not part of it.
+func writeManifest(dir string, started time.Time, subcommand, depositFile string, fs *flag.FlagSet) error {
+	manifest := runManifest{
+		Started:     started.UTC().Format(time.RFC3339),
+		Subcommand:  subcommand,
+		DepositFile: depositFile,
+		Flags:       make(map[string]string),
+	}
+	if depositFile != "" {
+		if data, err := os.ReadFile(depositFile); err == nil {
+			sum := sha256.Sum256(data)
+			manifest.DepositSHA256 = hex.EncodeToString(sum[:])
+		}
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		manifest.Flags[f.Name] = f.Value.String()
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "manifest.json"), data, 0o644)
+}
@@ -0,0 +1,186 @@
+//go:build cgo
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/miekg/pkcs11"
+)
+
+// secp256k1OID is the DER encoded CKA_EC_PARAMS of a secp256k1 key,
+// the named curve 1.3.132.0.10.
+var secp256k1OID = []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x0a}
+
+// pkcs11Signer signs with a secp256k1 key that never leaves an HSM.
+type pkcs11Signer struct {
+	mu      sync.Mutex
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	key     pkcs11.ObjectHandle
+	pubkey  []byte
+	address common.Address
+}
+
+// openPKCS11 logs in to slot of the PKCS#11 module at modulePath with pin and
+// finds the secp256k1 key pair labelled label, or the only one of the token
+// if label is empty.
+func openPKCS11(modulePath string, slot uint, pin, label string) (TxSigner, error) {
+	ctx := pkcs11.New(modulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %s", modulePath)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("failed to initialize PKCS#11 module %s: %w", modulePath, err)
+	}
+	s := &pkcs11Signer{ctx: ctx}
+	if err := s.open(slot, pin, label); err != nil {
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *pkcs11Signer) open(slot uint, pin, label string) error {
+	session, err := s.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return fmt.Errorf("failed to open a session on PKCS#11 slot %d: %w", slot, err)
+	}
+	s.session = session
+	if err := s.ctx.Login(session, pkcs11.CKU_USER, pin); err != nil {
+		s.ctx.CloseSession(session)
+		// The error is a CKR code and never holds the pin
+		return fmt.Errorf("failed to log in to PKCS#11 slot %d: %w", slot, err)
+	}
+
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+	}
+	if label != "" {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_LABEL, label))
+	}
+	key, err := s.findOne(template, "private key")
+	if err == nil {
+		s.key = key
+		err = s.loadPublicKey()
+	}
+	if err != nil {
+		s.ctx.Logout(session)
+		s.ctx.CloseSession(session)
+		return err
+	}
+	return nil
+}
+
+// findOne returns the single object of the token matching template.
+func (s *pkcs11Signer) findOne(template []*pkcs11.Attribute, what string) (pkcs11.ObjectHandle, error) {
+	if err := s.ctx.FindObjectsInit(s.session, template); err != nil {
+		return 0, fmt.Errorf("failed to search the PKCS#11 token: %w", err)
+	}
+	objects, _, err := s.ctx.FindObjects(s.session, 2)
+	if finalErr := s.ctx.FindObjectsFinal(s.session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to search the PKCS#11 token: %w", err)
+	}
+	switch len(objects) {
+	case 0:
+		return 0, fmt.Errorf("no EC %s on the PKCS#11 token", what)
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, fmt.Errorf("several EC %ss on the PKCS#11 token, select one with --pkcs11-key-label", what)
+	}
+}
+
+// loadPublicKey reads the public key paired with s.key by CKA_ID and checks
+// that it is on secp256k1.
+func (s *pkcs11Signer) loadPublicKey() error {
+	attrs, err := s.ctx.GetAttributeValue(s.session, s.key, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_ID, nil)})
+	if err != nil {
+		return fmt.Errorf("failed to read the CKA_ID of the private key: %w", err)
+	}
+	public, err := s.findOne([]*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, attrs[0].Value),
+	}, "public key")
+	if err != nil {
+		return err
+	}
+	attrs, err = s.ctx.GetAttributeValue(s.session, public, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read the public key: %w", err)
+	}
+	if !bytes.Equal(attrs[0].Value, secp256k1OID) {
+		return errors.New("the PKCS#11 key is not on the secp256k1 curve")
+	}
+	pubkey, err := parseECPoint(attrs[1].Value)
+	if err != nil {
+		return err
+	}
+	s.pubkey = crypto.FromECDSAPub(pubkey)
+	s.address = crypto.PubkeyToAddress(*pubkey)
+	return nil
+}
+
+// parseECPoint parses a CKA_EC_POINT: an uncompressed point, DER encoded as
+// an OCTET STRING by the standard but raw in some modules.
+func parseECPoint(value []byte) (*ecdsa.PublicKey, error) {
+	var point []byte
+	if rest, err := asn1.Unmarshal(value, &point); err != nil || len(rest) != 0 {
+		point = value
+	}
+	pubkey, err := crypto.UnmarshalPubkey(point)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PKCS#11 public key: %w", err)
+	}
+	return pubkey, nil
+}
+
+func (s *pkcs11Signer) Address() common.Address {
+	return s.address
+}
+
+func (s *pkcs11Signer) SignHash(hash []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil {
+		return nil, errors.New("the PKCS#11 session is closed")
+	}
+	if err := s.ctx.SignInit(s.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}, s.key); err != nil {
+		return nil, fmt.Errorf("failed to sign with the PKCS#11 key: %w", err)
+	}
+	rs, err := s.ctx.Sign(s.session, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with the PKCS#11 key: %w", err)
+	}
+	return recoverableSignature(hash, rs, s.pubkey)
+}
+
+func (s *pkcs11Signer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil {
+		return
+	}
+	s.ctx.Logout(s.session)
+	s.ctx.CloseSession(s.session)
+	s.ctx.Finalize()
+	s.ctx.Destroy()
+	s.ctx = nil
+}
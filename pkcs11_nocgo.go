@@ -0,0 +1,9 @@
+//go:build !cgo
+
+package main
+
+import "errors"
+
+func openPKCS11(string, uint, string, string) (TxSigner, error) {
+	return nil, errors.New("--signer pkcs11 needs a build with cgo")
+}
@@ -0,0 +1,34 @@
+//go:build cgo
+
+package main
+
+import (
+	"encoding/asn1"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestParseECPoint(t *testing.T) {
+	key, err := parsePrivateKey(testPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	point := crypto.FromECDSAPub(&key.PublicKey)
+	der, err := asn1.Marshal(point)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string][]byte{"DER": der, "raw": point} {
+		pubkey, err := parseECPoint(value)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if from := crypto.PubkeyToAddress(*pubkey).Hex(); from != "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+			t.Errorf("%s: address %s", name, from)
+		}
+	}
+	if _, err := parseECPoint(point[:33]); err == nil {
+		t.Error("parsed a truncated point")
+	}
+}
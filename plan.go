@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// planLogChunk is the block range of one eth_getLogs request when looking for
+// existing deposits, small enough for most providers.
+const planLogChunk = 10000
+
+const (
+	planActionNew   = "new"
+	planActionTopUp = "top-up"
+)
+
+// planEntry is what apply would do with one entry of the deposit file.
+type planEntry struct {
+	Index   int
+	PubKey  string
+	Action  string
+	Amount  *big.Int // gwei
+	Gas     uint64
+	Cost    *big.Int // wei
+	Problem string
+}
+
+// depositedPubkeys scans the DepositEvent logs of the contract from fromBlock
+// on and returns the normalized pubkeys that already have a deposit.
+func depositedPubkeys(ctx context.Context, client *ethclient.Client, contractABI abi.ABI, address common.Address, fromBlock uint64) (map[string]bool, error) {
+	event, ok := contractABI.Events["DepositEvent"]
+	if !ok {
+		return nil, errors.New("ABI has no DepositEvent")
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the latest block: %w", err)
+	}
+
+	pubkeys := make(map[string]bool)
+	for from := fromBlock; from <= head; from += planLogChunk {
+		to := min(from+planLogChunk-1, head)
+		logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: []common.Address{address},
+			Topics:    [][]common.Hash{{event.ID}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deposit logs of blocks %d-%d: %w", from, to, err)
+		}
+		for i := range logs {
+			deposit, err := decodeDepositEvent(event, &logs[i])
+			if err != nil {
+				return nil, err
+			}
+			pubkeys[hex.EncodeToString(deposit.PubKey)] = true
+		}
+	}
+	return pubkeys, nil
+}
+
+// checkDepositDataRoot recomputes the deposit data root of an entry.
+func checkDepositDataRoot(data DepositData) error {
+	if err := data.checkFieldLengths(); err != nil {
+		return err
+	}
+	if !data.Amount.IsUint64() {
+		return fmt.Errorf("amount %s gwei does not fit in 64 bits", data.Amount.String())
+	}
+	computed, err := computeDepositDataRoot(data.PubKey, data.WithdrawalCredentials, data.Amount.Uint64(), data.Signature)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(computed[:], data.DepositDataRoot) {
+		return fmt.Errorf("deposit_data_root is %x, the deposit data hashes to %x", []byte(data.DepositDataRoot), computed)
+	}
+	return nil
+}
+
+// depositMsg is the call of a single deposit, for simulation.
+func (s *Submitter) depositMsg(data DepositData) (ethereum.CallMsg, error) {
+	if err := data.checkFieldLengths(); err != nil {
+		return ethereum.CallMsg{}, err
+	}
+	packedData, err := s.call.Pack(data.PubKey, data.WithdrawalCredentials, data.Signature, data.root())
+	if err != nil {
+		return ethereum.CallMsg{}, fmt.Errorf("failed to pack arguments: %w", err)
+	}
+	value, err := s.depositValue(data)
+	if err != nil {
+		return ethereum.CallMsg{}, err
+	}
+	to := s.cfg.DepositAddress()
+	return ethereum.CallMsg{From: s.from, To: &to, Value: value, Data: packedData}, nil
+}
+
+// EstimateGas simulates a deposit with eth_estimateGas and explains a revert.
+func (s *Submitter) EstimateGas(ctx context.Context, data DepositData) (uint64, error) {
+	msg, err := s.depositMsg(data)
+	if err != nil {
+		return 0, err
+	}
+	gas, err := s.client.EstimateGas(ctx, msg)
+	if err != nil {
+		return 0, errors.New(s.explain(revertReason(err)))
+	}
+	return gas, nil
+}
+
+// Plan validates and simulates every deposit without sending anything.
+// existing holds the pubkeys that already have a deposit on-chain.
+func (s *Submitter) Plan(ctx context.Context, deposits []DepositData, existing map[string]bool, gasPrice *big.Int) []planEntry {
+	seen := make(map[string]bool)
+	var plan []planEntry
+	for _, data := range deposits {
+		pubkey := data.PubKey.String()
+		entry := planEntry{Index: data.index, PubKey: pubkey, Action: planActionNew, Amount: &data.Amount, Cost: new(big.Int)}
+		if existing[pubkey] || seen[pubkey] {
+			entry.Action = planActionTopUp
+		}
+		seen[pubkey] = true
+
+		if err := checkDepositDataRoot(data); err != nil {
+			entry.Problem = err.Error()
+		} else if gas, err := s.EstimateGas(ctx, data); err != nil {
+			entry.Problem = err.Error()
+		} else {
+			entry.Gas = gas
+			entry.Cost.Mul(new(big.Int).SetUint64(gas), gasPrice)
+		}
+		plan = append(plan, entry)
+	}
+	return plan
+}
+
+// printPlan writes the plan table and its totals.
+func printPlan(w io.Writer, plan []planEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tPUBKEY\tACTION\tAMOUNT (ETH)\tGAS\tCOST (ETH)\tPROBLEM")
+	amount, cost := new(big.Int), new(big.Int)
+	problems := 0
+	for _, e := range plan {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\t%s\t%s\n", e.Index, shortPubkey(e.PubKey), e.Action, formatGweiAsETH(e.Amount), e.Gas, formatWeiAsETH(e.Cost), e.Problem)
+		amount.Add(amount, e.Amount)
+		cost.Add(cost, e.Cost)
+		if e.Problem != "" {
+			problems++
+		}
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d deposits, %s ETH, estimated fees %s ETH, %d with problems\n", len(plan), formatGweiAsETH(amount), formatWeiAsETH(cost), problems)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// pollPolicy is how often a wait polls the node: first after Min, then
+// Factor times longer than the previous interval, up to Max. Long waits
+// thus cost few requests while short ones stay responsive. A receipt wait
+// gives up after ReceiptTimeout unless it is 0.
+type pollPolicy struct {
+	Min, Max       time.Duration
+	Factor         float64
+	ReceiptTimeout time.Duration
+}
+
+// next returns the interval after interval, Min for the first one.
+func (p pollPolicy) next(interval time.Duration) time.Duration {
+	if interval == 0 {
+		return p.Min
+	}
+	return min(time.Duration(float64(interval)*p.Factor), p.Max)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// waitMined waits for the receipt of tx like bind.WaitMined, but polls with
+// poll and asks for the receipt only once per new head, as it cannot appear
+// in between. Errors other than ctx ending are retried until
+// poll.ReceiptTimeout.
+func waitMined(ctx context.Context, client *ethclient.Client, tx *types.Transaction, poll pollPolicy) (*types.Receipt, error) {
+	parent := ctx
+	if poll.ReceiptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, poll.ReceiptTimeout)
+		defer cancel()
+	}
+	var checked uint64
+	var interval time.Duration
+	for {
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			slog.Debug("failed to get the latest block", "tx", tx.Hash().Hex(), "err", err)
+		} else if head != checked {
+			checked = head
+			receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+			if err == nil {
+				return receipt, nil
+			}
+			if !errors.Is(err, ethereum.NotFound) {
+				slog.Debug("failed to get the receipt", "tx", tx.Hash().Hex(), "err", err)
+			}
+		}
+		interval = poll.next(interval)
+		if err := sleepContext(ctx, interval); err != nil {
+			if parent.Err() == nil {
+				return nil, fmt.Errorf("no receipt within --receipt-timeout %s: %w", poll.ReceiptTimeout, err)
+			}
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	preflightDuplicate = "duplicate"
+	preflightInvalid   = "invalid"
+)
+
+// preflightRow is one line of the pre-flight table.
+type preflightRow struct {
+	index       int
+	pubkey      string
+	credentials string
+	amount      *big.Int // gwei
+	action      string
+	detail      string
+}
+
+// credentialsPrefix shows the withdrawal credential type, and for execution
+// address credentials the address.
+func credentialsPrefix(credentials string) string {
+	credentials = strings.ToLower(strings.TrimPrefix(credentials, "0x"))
+	if len(credentials) != 2*withdrawalCredentialsLength {
+		return "?"
+	}
+	if strings.HasPrefix(credentials, "00") {
+		return "0x00 (BLS)"
+	}
+	return "0x" + credentials[:2] + " 0x" + credentials[24:28] + "…" + credentials[60:]
+}
+
+// printPreflight writes the table of every entry of the run before anything
+// is confirmed: the entries to submit as new deposits, top-ups of a pubkey
+// deposited to earlier in the file, repeated or invalid entries, and the
+// entries of the file that results has as skipped, with the reason.
+// problems are those ValidateBatch found in deposits.
+func printPreflight(w io.Writer, entries, deposits []DepositData, results []Result, problems []EntryError) {
+	var rows []preflightRow
+	reasons := make(map[int]string)
+	for _, r := range results {
+		if r.Status == outcomeSkipped {
+			reasons[r.Index] = r.Detail
+		}
+	}
+	for _, data := range entries {
+		if reason, ok := reasons[data.index]; ok {
+			rows = append(rows, preflightRow{data.index, data.PubKey.String(), credentialsPrefix(data.WithdrawalCredentials.String()), &data.Amount, outcomeSkipped, reason})
+		}
+	}
+
+	invalid := make(map[int]string)
+	for _, p := range problems {
+		if _, ok := invalid[p.Index]; !ok {
+			invalid[p.Index] = p.Err.Error()
+		}
+	}
+	_, duplicates := dedupeDeposits(deposits)
+	repeated := make(map[int]int)
+	for _, d := range duplicates {
+		repeated[d.Index] = d.FirstIndex
+	}
+	seen := make(map[string]bool)
+	total, submitted := new(big.Int), 0
+	for _, data := range deposits {
+		pubkey := data.PubKey.String()
+		row := preflightRow{data.index, pubkey, credentialsPrefix(data.WithdrawalCredentials.String()), &data.Amount, planActionNew, ""}
+		if problem, ok := invalid[data.index]; ok {
+			row.action, row.detail = preflightInvalid, problem
+		} else if first, ok := repeated[data.index]; ok {
+			row.action, row.detail = preflightDuplicate, fmt.Sprintf("repeats entry %d", first)
+		} else if seen[pubkey] {
+			row.action = planActionTopUp
+		}
+		seen[pubkey] = true
+		if row.action != preflightInvalid {
+			total.Add(total, &data.Amount)
+			submitted++
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].index < rows[j].index })
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tPUBKEY\tCREDENTIALS\tAMOUNT (ETH)\tACTION\tDETAIL")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", r.index, shortPubkey(r.pubkey), r.credentials, formatGweiAsETH(r.amount), r.action, r.detail)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d deposits to submit, %s ETH; %d entries skipped or invalid\n\n", submitted, formatGweiAsETH(total), len(rows)-submitted)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// depositProfile captures how a network pays for a deposit. The Ethereum
+// profile sends the amount as msg.value; networks that deposit a token
+// instead (e.g. GNO on Gnosis chain, which also needs a token approval and a
+// different deposit method) implement their own profile and register it in
+// the network table.
+type depositProfile interface {
+	Name() string
+	// Value returns the wei sent as msg.value with a deposit of amountGwei.
+	Value(amountGwei *big.Int) *big.Int
+}
+
+type ethereumProfile struct{}
+
+func (ethereumProfile) Name() string {
+	return "ethereum"
+}
+
+func (ethereumProfile) Value(amountGwei *big.Int) *big.Int {
+	// GWEI to WEI
+	return new(big.Int).Mul(amountGwei, weiPerGwei)
+}
+
+// weiPerGwei is the wei in a gwei, the unit of deposit amounts.
+var weiPerGwei = big.NewInt(1e9)
+
+// checkDepositValue checks that valueWei is exactly amountGwei in wei, as
+// the deposit contract rejects any other msg.value. The wei are spelled out
+// from the decimal digits of the amount instead of multiplied, so that the
+// check does not repeat the conversion it checks.
+func checkDepositValue(amountGwei, valueWei *big.Int) error {
+	want, ok := new(big.Int).SetString(amountGwei.String()+"000000000", 10)
+	if !ok {
+		return fmt.Errorf("invalid amount %s gwei", amountGwei)
+	}
+	if valueWei.Cmp(want) != 0 {
+		return fmt.Errorf("value %s wei does not match the amount of %s gwei, %s wei", valueWei, amountGwei, want)
+	}
+	return nil
+}
+
+// depositValue is the msg.value of data. The value of a profile paying in
+// ETH is checked against the amount, so that a conversion bug fails here
+// and not in the contract.
+func (s *Submitter) depositValue(data DepositData) (*big.Int, error) {
+	value := s.profile.Value(&data.Amount)
+	if _, ok := s.profile.(ethereumProfile); ok {
+		if err := checkDepositValue(&data.Amount, value); err != nil {
+			return nil, fmt.Errorf("deposit %d: %w", data.index, err)
+		}
+	}
+	return value, nil
+}
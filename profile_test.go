@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSubmitValue(t *testing.T) {
+	aboveUint64, _ := new(big.Int).SetString("40000000000000000000", 10)
+	tests := []struct {
+		gwei *big.Int
+		wei  string
+	}{
+		{big.NewInt(32_000_000_000), "32000000000000000000"},
+		{big.NewInt(1_000_000_000), "1000000000000000000"},
+		{big.NewInt(40_000_000_000), "40000000000000000000"},
+		// 4e19 gwei does not fit a uint64, nor does its value in wei
+		{aboveUint64, "40000000000000000000000000000"},
+	}
+	node := newFakeNode(t, holeskyChainID)
+	submitter := node.submitter(t, nil)
+	for _, tt := range tests {
+		t.Run(tt.gwei.String(), func(t *testing.T) {
+			data := testDepositData(t, 0x11, 32_000_000_000)
+			data.Amount = *new(big.Int).Set(tt.gwei)
+			tx, err := submitter.Submit(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := new(big.Int).SetString(tt.wei, 10)
+			if tx.Value().Cmp(want) != 0 {
+				t.Errorf("value of %s gwei is %s wei, want %s", tt.gwei, tx.Value(), want)
+			}
+			if data.Amount.Cmp(tt.gwei) != 0 {
+				t.Errorf("amount changed to %s gwei", &data.Amount)
+			}
+		})
+	}
+}
+
+func TestCheckDepositValue(t *testing.T) {
+	aboveUint64, _ := new(big.Int).SetString("40000000000000000000", 10)
+	aboveUint64Wei, _ := new(big.Int).SetString("40000000000000000000000000000", 10)
+	tests := []struct {
+		name  string
+		gwei  *big.Int
+		wei   *big.Int
+		valid bool
+	}{
+		{"32 ETH", big.NewInt(32_000_000_000), new(big.Int).Mul(big.NewInt(32), big.NewInt(1e18)), true},
+		{"1 gwei", big.NewInt(1), big.NewInt(1_000_000_000), true},
+		{"above uint64", aboveUint64, aboveUint64Wei, true},
+		{"zero", big.NewInt(0), big.NewInt(0), true},
+		{"value in gwei", big.NewInt(32_000_000_000), big.NewInt(32_000_000_000), false},
+		{"sub-gwei remainder", big.NewInt(1), big.NewInt(1_000_000_001), false},
+		{"one gwei short", big.NewInt(2), big.NewInt(1_000_000_000), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDepositValue(tt.gwei, tt.wei)
+			if tt.valid && err != nil {
+				t.Errorf("%s wei for %s gwei: %v", tt.wei, tt.gwei, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("%s wei for %s gwei passed", tt.wei, tt.gwei)
+			}
+		})
+	}
+}
+
+func TestDepositValueCatchesConversionBugs(t *testing.T) {
+	// A wrong conversion factor is caught although Value uses it too
+	defer func(saved *big.Int) { weiPerGwei = saved }(weiPerGwei)
+	weiPerGwei = big.NewInt(1e8)
+
+	s := &Submitter{profile: ethereumProfile{}}
+	data := testDepositData(t, 0x11, 32_000_000_000)
+	if value, err := s.depositValue(data); err == nil {
+		t.Fatalf("32 ETH deposit with a value of %s wei passed", value)
+	}
+}
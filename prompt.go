@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stdin is shared by every prompt so that buffered input is never lost
+// between them. It is only read by the goroutine started by startInput, a
+// prompt that timed out leaves its line for the next one.
+var (
+	stdin      = bufio.NewReader(os.Stdin)
+	inputLines chan inputLine
+	inputOnce  sync.Once
+)
+
+type inputLine struct {
+	text string
+	err  error
+}
+
+func startInput() {
+	inputOnce.Do(func() {
+		inputLines = make(chan inputLine)
+		go func() {
+			defer close(inputLines)
+			for {
+				line, err := stdin.ReadString('\n')
+				inputLines <- inputLine{line, err}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	})
+}
+
+// readInput reads a raw line from stdin, the error is io.EOF at the end of
+// the input.
+func readInput() (string, error) {
+	startInput()
+	line, ok := <-inputLines
+	if !ok {
+		return "", io.EOF
+	}
+	return line.text, line.err
+}
+
+// readLine reads a line from stdin without surrounding whitespace. It returns
+// an empty string at EOF.
+func readLine() string {
+	line, _ := readInput()
+	return strings.TrimSpace(line)
+}
+
+// readLineTimeout is readLine giving up after timeout, 0 waits forever. It
+// reports false if the timeout expired.
+func readLineTimeout(timeout time.Duration) (string, bool) {
+	if timeout <= 0 {
+		return readLine(), true
+	}
+	startInput()
+	select {
+	case line := <-inputLines:
+		return strings.TrimSpace(line.text), true
+	case <-time.After(timeout):
+		return "", false
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// rateLimiter is a token bucket shared by every request sent to the node.
+// It holds at most one second's worth of tokens.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rps float64) *rateLimiter {
+	burst := math.Max(1, rps)
+	return &rateLimiter{rate: rps, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// Wait takes a token, sleeping until one is available, and reports how long it slept.
+func (l *rateLimiter) Wait(ctx context.Context) (time.Duration, error) {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+type rateLimitedTransport struct {
+	limiter   *rateLimiter
+	next      http.RoundTripper
+	throttled sync.Once
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay, err := t.limiter.Wait(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	if delay > 0 {
+		t.throttled.Do(func() {
+			log.Printf("RPC rate limit reached, throttling requests to %g per second", t.limiter.rate)
+		})
+	}
+	return t.next.RoundTrip(req)
+}
+
+// connectionTransport is the default transport capped to maxConns
+// connections per host. Idle connections are kept up to the same number, so
+// that concurrent requests reuse them instead of opening new ones; Go keeps
+// only two per host by default.
+func connectionTransport(maxConns int) http.RoundTripper {
+	if maxConns <= 0 {
+		return http.DefaultTransport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = maxConns
+	transport.MaxIdleConnsPerHost = maxConns
+	transport.MaxIdleConns = maxConns
+	return transport
+}
+
+// dialClient connects to the node over HTTP connections capped by
+// cfg.MaxConnections, routing requests through the rate limiter when cfg.RPS
+// is set. WebSocket and IPC endpoints are neither capped nor throttled.
+func dialClient(cfg Config) (*ethclient.Client, error) {
+	transport := connectionTransport(cfg.MaxConnections)
+	if cfg.RPS > 0 {
+		transport = &rateLimitedTransport{limiter: newRateLimiter(cfg.RPS), next: transport}
+	}
+	client, err := rpc.DialOptions(context.Background(), cfg.RPCURL, rpc.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RPS > 0 {
+		fmt.Printf("RPC calls limited to %g per second\n", cfg.RPS)
+	}
+	return ethclient.NewClient(client), nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// pendingDeposit is a deposit that was sent but whose receipt was not fetched yet.
+type pendingDeposit struct {
+	index int
+	data  DepositData
+	tx    *types.Transaction
+}
+
+type depositReceipt struct {
+	pendingDeposit
+	receipt *types.Receipt
+	err     error
+}
+
+// collectReceipts waits for the pending deposits with at most workers
+// concurrent pollers. Results are returned in the order of pending.
+func collectReceipts(ctx context.Context, client *ethclient.Client, pending []pendingDeposit, workers int, poll pollPolicy) []depositReceipt {
+	results := make([]depositReceipt, len(pending))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	var done atomic.Int64
+
+	for i, p := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			receipt, err := waitMined(ctx, client, p.tx, poll)
+			results[i] = depositReceipt{pendingDeposit: p, receipt: receipt, err: err}
+
+			n := done.Add(1)
+			if err != nil {
+				fmt.Printf("[%d/%d] deposit %d: %v\n", n, len(pending), p.index, err)
+				return
+			}
+			fmt.Printf("[%d/%d] deposit %d mined in block %d with status %d\n", n, len(pending), p.index, receipt.BlockNumber, receipt.Status)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// waitForCapacity waits for the oldest of the outstanding transactions to be
+// mined until fewer than limit are left, and returns those. A limit of 0
+// means no limit. Receipt errors are left to collectReceipts.
+func waitForCapacity(ctx context.Context, client *ethclient.Client, outstanding []*types.Transaction, limit int, poll pollPolicy) []*types.Transaction {
+	if limit <= 0 {
+		return outstanding
+	}
+	for len(outstanding) >= limit {
+		oldest := outstanding[0]
+		fmt.Printf("Waiting for capacity: %d transactions pending, waiting for %s\n", len(outstanding), oldest.Hash().Hex())
+		if _, err := waitMined(ctx, client, oldest, poll); err != nil {
+			fmt.Printf("Failed to wait for %s: %v\n", oldest.Hash().Hex(), err)
+		}
+		outstanding = outstanding[1:]
+	}
+	return outstanding
+}
+
+// receiptRecord is the --receipt-output-dir file of a deposit.
+type receiptRecord struct {
+	PubKey      string             `json:"pubkey"`
+	Index       int                `json:"index"`
+	Transaction *types.Transaction `json:"transaction"`
+	Receipt     *types.Receipt     `json:"receipt"`
+}
+
+// writeReceiptFile saves the transaction and receipt of the deposit data
+// to dir/<pubkey>.json. Another deposit for the same validator, a top-up,
+// gets a numbered file next to it instead of replacing the first.
+func writeReceiptFile(dir string, data DepositData, tx *types.Transaction, receipt *types.Receipt) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	js, err := json.MarshalIndent(receiptRecord{PubKey: data.PubKey.String(), Index: data.index, Transaction: tx, Receipt: receipt}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	pubkey := data.PubKey.String()
+	path := filepath.Join(dir, pubkey+".json")
+	for n := 2; ; n++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d.json", pubkey, n))
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := file.Write(js); err != nil {
+			file.Close()
+			return "", err
+		}
+		return path, file.Close()
+	}
+}
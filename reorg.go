@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// maxReorgRounds bounds how often the receipts are re-checked after a reorg.
+const maxReorgRounds = 3
+
+// checkReorgs makes sure that every receipt is still in the canonical chain.
+// A receipt whose block was reorged out is polled again, and a transaction
+// the node no longer knows is rebroadcast first, but not once the chain is
+// past validUntil; it keeps its nonce and signature, so this can never
+// submit a deposit twice. It returns the number of receipts that changed.
+func checkReorgs(ctx context.Context, client *ethclient.Client, results []depositReceipt, validUntil uint64, poll pollPolicy) int {
+	changed := 0
+	for round := 0; round < maxReorgRounds; round++ {
+		reorged := 0
+		for i := range results {
+			r := &results[i]
+			if r.err != nil {
+				continue
+			}
+			header, err := client.HeaderByNumber(ctx, r.receipt.BlockNumber)
+			if err != nil {
+				log.Printf("Warning: failed to check block %d of deposit %d for reorgs: %v", r.receipt.BlockNumber, r.index, err)
+				continue
+			}
+			if header.Hash() == r.receipt.BlockHash {
+				continue
+			}
+
+			reorged++
+			fmt.Printf("Reorg: block %d of deposit %d changed from %s to %s\n", r.receipt.BlockNumber, r.index, r.receipt.BlockHash.Hex(), header.Hash().Hex())
+			if _, _, err := client.TransactionByHash(ctx, r.tx.Hash()); errors.Is(err, ethereum.NotFound) {
+				if err := checkDeadline(ctx, client, validUntil); err != nil {
+					r.receipt, r.err = nil, fmt.Errorf("not rebroadcast after reorg: %w", err)
+					continue
+				}
+				fmt.Printf("Reorg: rebroadcasting deposit %d (%s)\n", r.index, r.tx.Hash().Hex())
+				if err := client.SendTransaction(ctx, r.tx); err != nil && !isAlreadyKnown(err) {
+					r.receipt, r.err = nil, fmt.Errorf("rebroadcast after reorg: %w", err)
+					continue
+				}
+			}
+			r.receipt, r.err = waitMined(ctx, client, r.tx, poll)
+			if r.err == nil {
+				fmt.Printf("Reorg: deposit %d mined again in block %d with status %d\n", r.index, r.receipt.BlockNumber, r.receipt.Status)
+			}
+		}
+		if reorged == 0 {
+			return changed
+		}
+		changed += reorged
+	}
+	log.Printf("Warning: receipts still changing after %d reorg checks", maxReorgRounds)
+	return changed
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// readReport reads a --summary-file written by an earlier run, with or
+// without --gas-report.
+func readReport(path string) ([]entryOutcome, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report []entryOutcome
+	if err := json.Unmarshal(data, &report); err == nil {
+		return report, nil
+	}
+	var withGas reportWithGas
+	if err := json.Unmarshal(data, &withGas); err != nil {
+		return nil, err
+	}
+	return withGas.Entries, nil
+}
+
+// isRetryable reports whether an entry with this outcome never made it
+// on-chain and can be submitted again. Unverified deposits were mined, and
+// deposits in flight may still be.
+func isRetryable(outcome string) bool {
+	return outcome == outcomeReverted || outcome == outcomeFailedValidation || outcome == outcomeFailedNetwork
+}
+
+// selectFailed splits deposits into the entries that failed or reverted in
+// report and the outcomes of the others, which are carried over to the
+// updated report. Entries missing from the report are neither.
+func selectFailed(deposits []DepositData, report []entryOutcome) (retry []DepositData, carried []entryOutcome, missing int, err error) {
+	byIndex := make(map[int]entryOutcome, len(report))
+	for _, e := range report {
+		byIndex[e.Index] = e
+	}
+	for _, data := range deposits {
+		e, ok := byIndex[data.index]
+		if !ok {
+			missing++
+			continue
+		}
+		if e.PubKey != data.PubKey.String() {
+			return nil, nil, 0, fmt.Errorf("entry %d of the report has pubkey %s, the deposit file %s", data.index, shortPubkey(e.PubKey), shortPubkey(data.PubKey.String()))
+		}
+		if isRetryable(e.Status) {
+			retry = append(retry, data)
+			continue
+		}
+		e.amount = &data.Amount
+		carried = append(carried, e)
+	}
+	return retry, carried, missing, nil
+}
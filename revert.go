@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// depositReverts maps the require messages of the beacon deposit contract to
+// the deposit data field that is most likely wrong.
+var depositReverts = []struct {
+	message string
+	field   string
+	hint    string
+}{
+	{"invalid pubkey length", "pubkey", "must be 48 bytes (96 hex characters)"},
+	{"invalid withdrawal_credentials length", "withdrawal_credentials", "must be 32 bytes (64 hex characters)"},
+	{"invalid signature length", "signature", "must be 96 bytes (192 hex characters)"},
+	{"deposit value too low", "amount", "must be at least 1000000000 gwei (1 ETH)"},
+	{"deposit value not multiple of gwei", "amount", "the transaction value must be a whole number of gwei"},
+	{"deposit value too high", "amount", "must fit in 64 bits of gwei"},
+	{"reconstructed DepositData does not match supplied deposit_data_root", "deposit_data_root",
+		"does not match pubkey, withdrawal_credentials, amount and signature, regenerate the deposit data"},
+	{"merkle tree full", "", "the contract accepts no more deposits"},
+}
+
+// senderRevertErrors are the custom errors that common access control
+// contracts use to reject a sender.
+var senderRevertErrors = []string{
+	"Unauthorized()",
+	"NotAuthorized()",
+	"NotAllowed()",
+	"SenderNotAllowed(address)",
+	"OwnableUnauthorizedAccount(address)",
+	"AccessControlUnauthorizedAccount(address,bytes32)",
+}
+
+// senderRevertMessages are revert message fragments, lowercased, of
+// allowlist and ownership checks.
+var senderRevertMessages = []string{
+	"unauthorized",
+	"not authorized",
+	"not allowed",
+	"not whitelisted",
+	"not allowlisted",
+	"not in allowlist",
+	"caller is not",
+	"is missing role",
+	"only owner",
+}
+
+// customErrorName returns the signature of a known custom error in revert data.
+func customErrorName(data []byte) (string, bool) {
+	if len(data) < 4 {
+		return "", false
+	}
+	for _, sig := range senderRevertErrors {
+		if bytes.Equal(crypto.Keccak256([]byte(sig))[:4], data[:4]) {
+			return sig, true
+		}
+	}
+	return "", false
+}
+
+// isSenderRejected tells whether a revert reason is an access control check
+// on the sender rather than a problem with the deposit data.
+func isSenderRejected(reason string) bool {
+	for _, sig := range senderRevertErrors {
+		if reason == sig {
+			return true
+		}
+	}
+	reason = strings.ToLower(reason)
+	for _, fragment := range senderRevertMessages {
+		if strings.Contains(reason, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// revertReason extracts the revert reason from an eth_call error.
+func revertReason(err error) string {
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if s, ok := dataErr.ErrorData().(string); ok {
+			if data, decodeErr := hexutil.Decode(s); decodeErr == nil {
+				if reason, unpackErr := abi.UnpackRevert(data); unpackErr == nil {
+					return reason
+				}
+				if name, ok := customErrorName(data); ok {
+					return name
+				}
+			}
+		}
+	}
+	msg := err.Error()
+	if i := strings.Index(msg, "execution reverted: "); i >= 0 {
+		return msg[i+len("execution reverted: "):]
+	}
+	return msg
+}
+
+// explainRevert turns a revert reason into an actionable message.
+func explainRevert(reason string) string {
+	for _, r := range depositReverts {
+		if !strings.Contains(reason, r.message) {
+			continue
+		}
+		if r.field == "" {
+			return fmt.Sprintf("%s: %s", reason, r.hint)
+		}
+		return fmt.Sprintf("%s: check %s, it %s", reason, r.field, r.hint)
+	}
+	return reason
+}
+
+// explain is explainRevert that, with --simulate or --verify-after-submit,
+// also recognizes a contract rejecting the sender on permissioned networks.
+func (s *Submitter) explain(reason string) string {
+	if (s.cfg.Simulate || s.cfg.VerifyAfterSubmit) && isSenderRejected(reason) {
+		return fmt.Sprintf("%s: the contract rejects sender %s, check that it is on the deposit allowlist", reason, s.from.Hex())
+	}
+	return explainRevert(reason)
+}
+
+// simulate runs the deposit call with eth_call and explains a revert.
+func (s *Submitter) simulate(ctx context.Context, msg ethereum.CallMsg) error {
+	if _, err := s.client.CallContract(ctx, msg, nil); err != nil {
+		return errors.New(s.explain(revertReason(err)))
+	}
+	return nil
+}
+
+// simulationFailure is an entry whose simulation reverted or could not run.
+type simulationFailure struct {
+	data DepositData
+	err  error
+}
+
+// SimulateAll runs every deposit through eth_call with --gas-limit and
+// returns all failures, in the order of deposits, so that a systematic
+// problem is found before anything is sent.
+func (s *Submitter) SimulateAll(ctx context.Context, deposits []DepositData) []simulationFailure {
+	var failures []simulationFailure
+	for _, data := range deposits {
+		msg, err := s.depositMsg(data)
+		if err == nil {
+			msg.Gas = s.gasLimit(data)
+			err = s.simulate(ctx, msg)
+		}
+		if err != nil {
+			failures = append(failures, simulationFailure{data: data, err: err})
+		}
+	}
+	return failures
+}
+
+// ReplayRevert re-executes a reverted deposit on the state before its block
+// to recover the revert reason.
+func (s *Submitter) ReplayRevert(ctx context.Context, tx *types.Transaction, receipt *types.Receipt) string {
+	msg := ethereum.CallMsg{From: s.from, To: tx.To(), Gas: tx.Gas(), Value: tx.Value(), Data: tx.Data()}
+	_, err := s.client.CallContract(ctx, msg, new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1)))
+	if err == nil {
+		return "the call succeeds when replayed, the revert depends on the transaction's position in the block"
+	}
+	return s.explain(revertReason(err))
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+const reviewHelp = `Commands:
+  t <n>          include or exclude entry n
+  a <n> <gwei>   change the amount of entry n
+  y              submit the included entries
+  q              abort`
+
+// reviewDeposits lets the operator exclude entries and change amounts before
+// anything is submitted, and returns the entries to submit. Entries are
+// numbered by their index in the deposit file. deposited reports whether a
+// pubkey already has a deposit on-chain, see changeAmount.
+func reviewDeposits(deposits []DepositData, deposited func(pubkey string) (bool, error)) ([]DepositData, error) {
+	included := make([]bool, len(deposits))
+	for i := range included {
+		included[i] = true
+	}
+
+	for {
+		fmt.Printf("\nDeposits to review:\n")
+		for i, data := range deposits {
+			mark := " "
+			if included[i] {
+				mark = "x"
+			}
+			fmt.Printf("  [%s] %d  %s  %s ETH\n", mark, data.index, shortPubkey(data.PubKey.String()), formatGweiAsETH(&data.Amount))
+		}
+		fmt.Printf("%s\n> ", reviewHelp)
+
+		line, err := readInput()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			if err != nil {
+				return nil, fmt.Errorf("review aborted: %w", err)
+			}
+			continue
+		}
+		switch fields[0] {
+		case "y":
+			var kept []DepositData
+			for i, data := range deposits {
+				if included[i] {
+					kept = append(kept, data)
+				}
+			}
+			return kept, nil
+		case "q":
+			return nil, fmt.Errorf("review aborted")
+		case "t":
+			i, err := reviewEntry(fields, 2, deposits)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			included[i] = !included[i]
+		case "a":
+			i, err := reviewEntry(fields, 3, deposits)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if err := changeAmount(&deposits[i], fields[2], deposited); err != nil {
+				fmt.Println(err)
+			}
+		default:
+			fmt.Printf("unknown command %q\n", fields[0])
+		}
+	}
+}
+
+// reviewEntry returns the position in deposits of the entry whose file index
+// is the first argument of the command.
+func reviewEntry(fields []string, want int, deposits []DepositData) (int, error) {
+	if len(fields) != want {
+		return 0, fmt.Errorf("%s expects %d arguments", fields[0], want-1)
+	}
+	index, err := strconv.Atoi(fields[1])
+	if err == nil {
+		for i, data := range deposits {
+			if data.index == index {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no entry %q", fields[1])
+}
+
+// changeAmount sets a new amount and recomputes the deposit data root so that
+// the contract accepts it, and drops the deposit message root of the old
+// amount. The signature covers the amount and the beacon
+// chain only ignores it for a top-up of an existing validator, so the amount
+// of a pubkey without a deposit on-chain is not changed: the deposit has to
+// be signed again for the new amount.
+func changeAmount(data *DepositData, gwei string, deposited func(pubkey string) (bool, error)) error {
+	amount, ok := new(big.Int).SetString(gwei, 10)
+	if !ok || amount.Sign() <= 0 || !amount.IsUint64() {
+		return fmt.Errorf("invalid amount %q, expected a positive number of gwei", gwei)
+	}
+	topUp, err := deposited(data.PubKey.String())
+	if err != nil {
+		return fmt.Errorf("cannot change the amount of entry %d: %w", data.index, err)
+	}
+	if !topUp {
+		return fmt.Errorf("entry %d has no deposit on-chain yet and its signature covers the amount, sign the deposit again for the new amount", data.index)
+	}
+
+	root, err := computeDepositDataRoot(data.PubKey, data.WithdrawalCredentials, amount.Uint64(), data.Signature)
+	if err != nil {
+		return err
+	}
+
+	data.Amount = *amount
+	data.DepositDataRoot = root[:]
+	data.DepositMessageRoot = ""
+	data.amountChanged = true
+	fmt.Printf("Warning: the signature does not cover the new amount, entry %d is only valid as a top-up of its existing validator\n", data.index)
+	return nil
+}
+
+// depositedLookup returns the deposited func of reviewDeposits. The first
+// call connects to the node and scans the DepositEvent logs of the deposit
+// contract from --plan-from-block on, like plan.
+func depositedLookup(cfg Config, contractABI abi.ABI) func(pubkey string) (bool, error) {
+	var once sync.Once
+	var existing map[string]bool
+	var err error
+	return func(pubkey string) (bool, error) {
+		once.Do(func() {
+			existing, err = lookUpDeposited(cfg, contractABI)
+		})
+		return existing[pubkey], err
+	}
+}
+
+func lookUpDeposited(cfg Config, contractABI abi.ABI) (map[string]bool, error) {
+	client, err := dialClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the Ethereum client: %w", err)
+	}
+	defer client.Close()
+	chainID, err := resolveChainID(context.Background(), client, cfg.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	n, knownNetwork := networkByChainID(chainID)
+	cfg.ResolveContract(n, knownNetwork)
+	address, _ := depositCheckAddress(cfg, n, knownNetwork)
+	fmt.Printf("Looking up existing deposits of %s...\n", address.Hex())
+	return depositedPubkeys(context.Background(), client, contractABI, address, cfg.PlanFromBlock)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// bundleTargetBlocks is the number of consecutive blocks a bundle is
+	// offered for before the run gives up.
+	bundleTargetBlocks = 5
+	// bundlePollInterval is how often the head is checked for the target block.
+	bundlePollInterval = time.Second
+)
+
+// bundledTx is a signed transaction held back for the --tx-send-bundle bundle.
+type bundledTx struct {
+	deposits []DepositData
+	tx       *types.Transaction
+}
+
+// bundleRelay talks to a block builder's eth_sendBundle endpoint. Requests
+// carry the Flashbots signature header, an EIP-191 signature of the body
+// hash by the sending account.
+type bundleRelay struct {
+	url    string
+	signer TxSigner
+	client *http.Client
+}
+
+type bundleParams struct {
+	Txs         []string `json:"txs"`
+	BlockNumber string   `json:"blockNumber"`
+}
+
+// sendBundle offers txs, in order, for inclusion in block only.
+func (r *bundleRelay) sendBundle(ctx context.Context, txs []*types.Transaction, block uint64) error {
+	params := bundleParams{BlockNumber: hexutil.EncodeUint64(block)}
+	for _, tx := range txs {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		params.Txs = append(params.Txs, hexutil.Encode(raw))
+	}
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "eth_sendBundle", "params": []bundleParams{params}})
+	if err != nil {
+		return err
+	}
+
+	hash := accounts.TextHash([]byte(hexutil.Encode(crypto.Keccak256(body))))
+	signature, err := r.signer.SignHash(hash)
+	if err != nil {
+		return fmt.Errorf("failed to sign the bundle request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Flashbots-Signature", r.signer.Address().Hex()+":"+hexutil.Encode(signature))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("eth_sendBundle request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	reply, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("eth_sendBundle returned %s: %s", resp.Status, bytes.TrimSpace(reply))
+	}
+	var result struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(reply, &result); err != nil {
+		return fmt.Errorf("invalid eth_sendBundle reply: %w", err)
+	}
+	if result.Error != nil {
+		return fmt.Errorf("eth_sendBundle failed: %s", result.Error.Message)
+	}
+	return nil
+}
+
+// SendBundle sends the transactions held back by --tx-send-bundle to the
+// builder as one bundle, offered for each of the next bundleTargetBlocks
+// blocks until it is included: either all of them land in one block or
+// none is published. A builder that does not take the bundle is a reason to
+// fall back to sending them one by one through the node, which publishes
+// the same signed transactions and so cannot deposit twice. It returns an
+// error if the bundle was not included.
+func (s *Submitter) SendBundle(ctx context.Context) error {
+	if len(s.bundle) == 0 {
+		return nil
+	}
+	txs := make([]*types.Transaction, len(s.bundle))
+	for i, b := range s.bundle {
+		txs[i] = b.tx
+	}
+	relay := &bundleRelay{url: s.cfg.TxSendBundle, signer: s.signer, client: &http.Client{Timeout: 10 * time.Second}}
+
+	head, err := s.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the latest block: %w", err)
+	}
+	last := txs[len(txs)-1]
+	first, lastTarget := head+1, head+bundleTargetBlocks
+	if s.cfg.ValidUntilBlock > 0 {
+		lastTarget = min(lastTarget, s.cfg.ValidUntilBlock)
+	}
+	for target := first; target <= lastTarget; target = head + 1 {
+		if err := relay.sendBundle(ctx, txs, target); err != nil {
+			log.Printf("Warning: the builder did not take the bundle, sending its %d transactions one by one: %v", len(txs), err)
+			return s.broadcastBundled(ctx)
+		}
+		fmt.Printf("Bundle of %d transactions offered for block %d\n", len(txs), target)
+
+		for head < target {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(bundlePollInterval):
+			}
+			if head, err = s.client.BlockNumber(ctx); err != nil {
+				return fmt.Errorf("failed to get the latest block: %w", err)
+			}
+		}
+		receipt, err := s.client.TransactionReceipt(ctx, last.Hash())
+		if err == nil {
+			fmt.Printf("Bundle included in block %d\n", receipt.BlockNumber)
+			for _, b := range s.bundle {
+				s.recordAll(b.deposits, statusBroadcast, b.tx)
+			}
+			return nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return fmt.Errorf("failed to check the bundle: %w", err)
+		}
+		fmt.Printf("Bundle not included in block %d\n", target)
+	}
+	if first > lastTarget {
+		return fmt.Errorf("bundle not offered: %w", checkDeadline(ctx, s.client, s.cfg.ValidUntilBlock))
+	}
+	return fmt.Errorf("bundle not included in blocks %d-%d, none of its transactions was published", first, lastTarget)
+}
+
+// broadcastBundled sends the held back transactions through the node in
+// nonce order.
+func (s *Submitter) broadcastBundled(ctx context.Context) error {
+	for _, b := range s.bundle {
+		if err := checkDeadline(ctx, s.client, s.cfg.ValidUntilBlock); err != nil {
+			return fmt.Errorf("not sending %s: %w", b.tx.Hash().Hex(), err)
+		}
+		if err := s.client.SendTransaction(ctx, b.tx); err != nil && !isAlreadyKnown(err) {
+			return fmt.Errorf("failed to send %s: %w", b.tx.Hash().Hex(), err)
+		}
+		s.recordAll(b.deposits, statusBroadcast, b.tx)
+		fmt.Printf("Transaction sent: %s\n", b.tx.Hash().Hex())
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	signerKey    = "key"
+	signerPKCS11 = "pkcs11"
+)
+
+// TxSigner holds the key transactions are signed with: PRIVATE_KEY, a
+// mnemonic or a key kept in an HSM.
+type TxSigner interface {
+	Address() common.Address
+	// SignHash returns the 65 byte [R || S || V] signature of hash, V being
+	// the recovery id 0 or 1, like crypto.Sign.
+	SignHash(hash []byte) ([]byte, error)
+	// Close releases the key; the signer cannot sign afterwards.
+	Close()
+}
+
+// newTxSigner opens the signer of --signer, checking it against
+// --expected-from.
+func newTxSigner(cfg Config) (TxSigner, error) {
+	if cfg.Signer != signerPKCS11 {
+		key, err := signingKey(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return keySigner{key}, nil
+	}
+	signer, err := openPKCS11(cfg.PKCS11Module, cfg.PKCS11Slot, cfg.PKCS11Pin, cfg.PKCS11KeyLabel)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ExpectedFrom != "" && signer.Address() != common.HexToAddress(cfg.ExpectedFrom) {
+		signer.Close()
+		return nil, fmt.Errorf("key is for %s, --expected-from is %s", signer.Address().Hex(), cfg.ExpectedFrom)
+	}
+	return signer, nil
+}
+
+// keySigner signs with a private key in memory.
+type keySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s keySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+func (s keySigner) SignHash(hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, s.key)
+}
+
+func (s keySigner) Close() {
+	wipePrivateKey(s.key)
+}
+
+// signTx signs tx for chainID with signer.
+func signTx(signer TxSigner, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	txSigner := types.LatestSignerForChainID(chainID)
+	sig, err := signer.SignHash(txSigner.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(txSigner, sig)
+}
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// recoverableSignature turns the 64 byte R || S signature of hash that an HSM
+// returns into the [R || S || V] form of crypto.Sign: S is moved to the
+// lower half of the curve order as Ethereum requires, and V is the recovery
+// id under which hash and the signature give back pubkey, the uncompressed
+// public key of the signing key.
+func recoverableSignature(hash, rs, pubkey []byte) ([]byte, error) {
+	if len(rs) != 64 {
+		return nil, fmt.Errorf("signature is %d bytes, expected 64", len(rs))
+	}
+	sig := make([]byte, 65)
+	copy(sig, rs[:32])
+	s := new(big.Int).SetBytes(rs[32:])
+	if s.Cmp(secp256k1HalfN) > 0 {
+		s.Sub(secp256k1N, s)
+	}
+	s.FillBytes(sig[32:64])
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		if recovered, err := crypto.Ecrecover(hash, sig); err == nil && bytes.Equal(recovered, pubkey) {
+			return sig, nil
+		}
+	}
+	return nil, errors.New("signature does not recover to the public key of the signing key")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestRecoverableSignature(t *testing.T) {
+	key, err := parsePrivateKey(testPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey := crypto.FromECDSAPub(&key.PublicKey)
+	other, _ := crypto.GenerateKey()
+
+	for i := range 8 {
+		hash := crypto.Keccak256([]byte{byte(i)})
+		want, err := crypto.Sign(hash, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// An HSM returns R || S with either S, and no recovery id
+		highS := new(big.Int).Sub(secp256k1N, new(big.Int).SetBytes(want[32:64]))
+		tests := []struct {
+			name string
+			rs   []byte
+		}{
+			{name: "low s", rs: want[:64]},
+			{name: "high s", rs: append(bytes.Clone(want[:32]), highS.FillBytes(make([]byte, 32))...)},
+		}
+		for _, tt := range tests {
+			got, err := recoverableSignature(hash, tt.rs, pubkey)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s: signature %x, want %x with recovery id %d", tt.name, got, want, want[64])
+			}
+		}
+
+		if _, err := recoverableSignature(hash, want[:64], crypto.FromECDSAPub(&other.PublicKey)); err == nil {
+			t.Error("a signature recovered to the public key of another key")
+		}
+	}
+
+	if _, err := recoverableSignature(make([]byte, 32), make([]byte, 70), pubkey); err == nil || err.Error() != "signature is 70 bytes, expected 64" {
+		t.Errorf("error %v for a DER encoded signature", err)
+	}
+}
+
+// hsmSigner stands in for an HSM: it signs with key but returns R || S with a
+// high S, as an HSM may.
+type hsmSigner struct {
+	keySigner
+}
+
+func (s hsmSigner) SignHash(hash []byte) ([]byte, error) {
+	sig, err := s.keySigner.SignHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	highS := new(big.Int).Sub(secp256k1N, new(big.Int).SetBytes(sig[32:64]))
+	return recoverableSignature(hash, append(sig[:32], highS.FillBytes(make([]byte, 32))...), crypto.FromECDSAPub(&s.key.PublicKey))
+}
+
+func TestSignTx(t *testing.T) {
+	key, err := parsePrivateKey(testPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainID := big.NewInt(holeskyChainID)
+	to := common.HexToAddress("0x4242424242424242424242424242424242424242")
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: 3, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000, To: &to, Value: big.NewInt(1)})
+	want, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, signer := range []TxSigner{keySigner{key}, hsmSigner{keySigner{key}}} {
+		signed, err := signTx(signer, tx, chainID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signed.Hash() != want.Hash() {
+			t.Errorf("%T signed %s, want %s", signer, signed.Hash().Hex(), want.Hash().Hex())
+		}
+		from, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+		if err != nil || from != signer.Address() {
+			t.Errorf("%T: sender %s (%v), want %s", signer, from.Hex(), err, signer.Address().Hex())
+		}
+	}
+}
+
+func TestValidateSigner(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cfg *Config)
+		err    string
+	}{
+		{name: "private key", change: func(cfg *Config) { cfg.PrivateKey = testPrivateKey }},
+		{name: "no key", change: func(*Config) {}, err: "PRIVATE_KEY is not set"},
+		{name: "pkcs11", change: func(cfg *Config) {
+			cfg.Signer, cfg.PKCS11Module, cfg.PKCS11Pin = signerPKCS11, "/usr/lib/softhsm/libsofthsm2.so", "1234"
+		}},
+		{name: "pkcs11 without module", change: func(cfg *Config) {
+			cfg.Signer, cfg.PKCS11Pin = signerPKCS11, "1234"
+		}, err: "--signer pkcs11 needs --pkcs11-module"},
+		{name: "pkcs11 without pin", change: func(cfg *Config) {
+			cfg.Signer, cfg.PKCS11Module = signerPKCS11, "/usr/lib/softhsm/libsofthsm2.so"
+		}, err: "PKCS11_PIN is not set"},
+		{name: "pkcs11 and a private key", change: func(cfg *Config) {
+			cfg.Signer, cfg.PKCS11Module, cfg.PKCS11Pin, cfg.PrivateKey = signerPKCS11, "/usr/lib/softhsm/libsofthsm2.so", "1234", testPrivateKey
+		}, err: "unset PRIVATE_KEY"},
+		{name: "unknown signer", change: func(cfg *Config) { cfg.Signer = "kms" }, err: `invalid --signer "kms"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.change(&cfg)
+			err := cfg.validateSigner()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error %v, want %q", err, tt.err)
+			}
+			if strings.Contains(err.Error(), "1234") {
+				t.Errorf("error %q holds the pin", err)
+			}
+		})
+	}
+}
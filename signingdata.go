@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// signingData is everything needed to verify a deposit signature offline.
+type signingData struct {
+	Index                 int      `json:"index"`
+	PubKey                HexBytes `json:"pubkey"`
+	WithdrawalCredentials HexBytes `json:"withdrawal_credentials"`
+	Amount                uint64   `json:"amount"`
+	ForkVersion           HexBytes `json:"fork_version"`
+	DepositMessageRoot    HexBytes `json:"deposit_message_root"`
+	Domain                HexBytes `json:"domain"`
+	SigningRoot           HexBytes `json:"signing_root"`
+	// Signature is copied as given, to be checked against the signing root.
+	Signature HexBytes `json:"signature"`
+}
+
+func parseForkVersion(s string) ([4]byte, error) {
+	var version [4]byte
+	b, err := decodeHex(s)
+	if err != nil {
+		return version, fmt.Errorf("invalid fork version %q: %w", s, err)
+	}
+	if len(b) != len(version) {
+		return version, fmt.Errorf("fork version must be 4 bytes, got %d", len(b))
+	}
+	copy(version[:], b)
+	return version, nil
+}
+
+// depositForkVersion is the entry's own fork_version, falling back to the
+// genesis fork version of the connected network.
+func depositForkVersion(data DepositData, n network, knownNetwork bool) ([4]byte, error) {
+	if data.ForkVersion != "" {
+		return parseForkVersion(data.ForkVersion)
+	}
+	if !knownNetwork {
+		return [4]byte{}, errors.New("fork_version is not set and the network is unknown")
+	}
+	return n.GenesisForkVersion, nil
+}
+
+func buildSigningData(data DepositData, forkVersion [4]byte) (signingData, error) {
+	if err := data.checkFieldLengths(); err != nil {
+		return signingData{}, err
+	}
+	if !data.Amount.IsUint64() {
+		return signingData{}, fmt.Errorf("amount %s does not fit in uint64", data.Amount.String())
+	}
+
+	messageRoot, err := depositMessageRoot(data.PubKey, data.WithdrawalCredentials, data.Amount.Uint64())
+	if err != nil {
+		return signingData{}, err
+	}
+	domain := computeDepositDomain(forkVersion)
+	signingRoot := computeSigningRoot(messageRoot, domain)
+
+	return signingData{
+		Index:                 data.index,
+		PubKey:                data.PubKey,
+		WithdrawalCredentials: data.WithdrawalCredentials,
+		Amount:                data.Amount.Uint64(),
+		ForkVersion:           forkVersion[:],
+		DepositMessageRoot:    messageRoot[:],
+		Domain:                domain[:],
+		SigningRoot:           signingRoot[:],
+		Signature:             data.Signature,
+	}, nil
+}
+
+// dumpSigningData writes the signing data of every deposit to path.
+func dumpSigningData(path string, deposits []DepositData, n network, knownNetwork bool) error {
+	out := make([]signingData, 0, len(deposits))
+	for _, data := range deposits {
+		forkVersion, err := depositForkVersion(data, n, knownNetwork)
+		if err != nil {
+			return fmt.Errorf("deposit %d: %w", data.index, err)
+		}
+		sd, err := buildSigningData(data, forkVersion)
+		if err != nil {
+			return fmt.Errorf("deposit %d: %w", data.index, err)
+		}
+		out = append(out, sd)
+	}
+
+	js, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, js, 0o644)
+}
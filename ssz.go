@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// Minimal SSZ hash_tree_root helpers for the consensus containers involved in
+// a deposit. See the phase0 beacon chain specification for their layout.
+
+const (
+	pubkeyLength                = 48
+	withdrawalCredentialsLength = 32
+	signatureLength             = 96
+	rootLength                  = 32
+)
+
+// domainDeposit is DOMAIN_DEPOSIT. Deposits are always signed with the
+// genesis fork version and an empty genesis validators root.
+var domainDeposit = [4]byte{0x03, 0x00, 0x00, 0x00}
+
+func hashPair(a, b [32]byte) [32]byte {
+	return sha256.Sum256(append(a[:], b[:]...))
+}
+
+// chunks splits b into 32-byte chunks, zero padding the last one.
+func chunks(b []byte) [][32]byte {
+	out := make([][32]byte, (len(b)+31)/32)
+	for i := range out {
+		copy(out[i][:], b[i*32:])
+	}
+	return out
+}
+
+// merkleize hashes the chunks pairwise up to a single root, padding the
+// leaves with zero chunks to the next power of two.
+func merkleize(leaves [][32]byte) [32]byte {
+	n := 1
+	for n < len(leaves) {
+		n *= 2
+	}
+	layer := make([][32]byte, n)
+	copy(layer, leaves)
+	for len(layer) > 1 {
+		next := make([][32]byte, len(layer)/2)
+		for i := range next {
+			next[i] = hashPair(layer[2*i], layer[2*i+1])
+		}
+		layer = next
+	}
+	return layer[0]
+}
+
+func uint64Chunk(v uint64) [32]byte {
+	var c [32]byte
+	binary.LittleEndian.PutUint64(c[:], v)
+	return c
+}
+
+func checkLengths(pubkey, withdrawalCredentials []byte) error {
+	if len(pubkey) != pubkeyLength {
+		return fmt.Errorf("pubkey must be %d bytes, got %d", pubkeyLength, len(pubkey))
+	}
+	if len(withdrawalCredentials) != withdrawalCredentialsLength {
+		return fmt.Errorf("withdrawal credentials must be %d bytes, got %d", withdrawalCredentialsLength, len(withdrawalCredentials))
+	}
+	return nil
+}
+
+// depositMessageRoot is hash_tree_root(DepositMessage).
+func depositMessageRoot(pubkey, withdrawalCredentials []byte, amountGwei uint64) ([32]byte, error) {
+	if err := checkLengths(pubkey, withdrawalCredentials); err != nil {
+		return [32]byte{}, err
+	}
+	return merkleize([][32]byte{
+		merkleize(chunks(pubkey)),
+		chunks(withdrawalCredentials)[0],
+		uint64Chunk(amountGwei),
+	}), nil
+}
+
+// computeDepositDataRoot is hash_tree_root(DepositData), the value the
+// deposit contract checks against deposit_data_root.
+func computeDepositDataRoot(pubkey, withdrawalCredentials []byte, amountGwei uint64, signature []byte) ([32]byte, error) {
+	if err := checkLengths(pubkey, withdrawalCredentials); err != nil {
+		return [32]byte{}, err
+	}
+	if len(signature) != signatureLength {
+		return [32]byte{}, fmt.Errorf("signature must be %d bytes, got %d", signatureLength, len(signature))
+	}
+	return merkleize([][32]byte{
+		merkleize(chunks(pubkey)),
+		chunks(withdrawalCredentials)[0],
+		uint64Chunk(amountGwei),
+		merkleize(chunks(signature)),
+	}), nil
+}
+
+// computeDepositDomain is compute_domain(DOMAIN_DEPOSIT, forkVersion, Root()).
+func computeDepositDomain(forkVersion [4]byte) [32]byte {
+	var version [32]byte
+	copy(version[:], forkVersion[:])
+	forkDataRoot := hashPair(version, [32]byte{})
+
+	var domain [32]byte
+	copy(domain[:4], domainDeposit[:])
+	copy(domain[4:], forkDataRoot[:28])
+	return domain
+}
+
+// computeSigningRoot is hash_tree_root(SigningData).
+func computeSigningRoot(objectRoot, domain [32]byte) [32]byte {
+	return hashPair(objectRoot, domain)
+}
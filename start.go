@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// startPollInterval is the longest interval between two checks of the latest
+// block while waiting for --start-at-block or --start-at-time, so that the
+// start is caught within one poll.
+const startPollInterval = 2 * time.Second
+
+// waitForStart blocks until the latest block is at least block and its
+// timestamp at least at; a zero block or time is not waited for. The node is
+// polled with poll, capped at startPollInterval, and failed polls are logged
+// and retried. It returns the block number the deposits start at.
+func waitForStart(ctx context.Context, client *ethclient.Client, block uint64, at time.Time, poll pollPolicy) (uint64, error) {
+	started := time.Now()
+	reported := false
+	var interval time.Duration
+	for {
+		interval = min(poll.next(interval), startPollInterval)
+		header, err := client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			log.Printf("Warning: failed to get the latest block, retrying in %s: %v", interval, err)
+			if err := sleepContext(ctx, interval); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		number := header.Number.Uint64()
+		blockTime := time.Unix(int64(header.Time), 0)
+		if number >= block && (at.IsZero() || !blockTime.Before(at)) {
+			fmt.Printf("Waited %s, starting at block %d (%s)\n", time.Since(started).Round(time.Second), number, blockTime.UTC().Format(time.RFC3339))
+			return number, nil
+		}
+
+		if !reported {
+			fmt.Printf("Waiting for the start: latest block is %d (%s)", number, blockTime.UTC().Format(time.RFC3339))
+			if block > number {
+				fmt.Printf(", start block %d", block)
+			}
+			if !at.IsZero() {
+				fmt.Printf(", start time %s", at.UTC().Format(time.RFC3339))
+			}
+			fmt.Println()
+			reported = true
+		}
+
+		if err := sleepContext(ctx, interval); err != nil {
+			return 0, err
+		}
+	}
+}
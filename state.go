@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Deposit statuses recorded in the state file. A deposit moves through
+// building, signed and broadcast before ending up in one of the mined
+// statuses, or reverted.
+const (
+	statusBuilding  = "building"
+	statusSigned    = "signed"
+	statusBroadcast = "broadcast"
+
+	statusConfirmed = "confirmed"
+	statusReverted  = "reverted"
+	// statusVerified and statusUnverified are used with --verify-after-submit
+	// for mined deposits that did or did not pass the post-submit checks.
+	statusVerified   = "verified"
+	statusUnverified = "unverified"
+)
+
+// isMined reports whether a deposit with this status made it on-chain and
+// must not be sent again.
+func isMined(status string) bool {
+	return status == statusConfirmed || status == statusVerified || status == statusUnverified
+}
+
+// isInFlight reports whether a deposit with this status was signed and may
+// already be on its way to the chain.
+func isInFlight(status string) bool {
+	return status == statusSigned || status == statusBroadcast
+}
+
+type depositRecord struct {
+	// Index is the entry of the deposit file the record is for.
+	Index  int    `json:"index"`
+	PubKey string `json:"pubkey"`
+	TxHash string `json:"tx_hash,omitempty"`
+	Status string `json:"status"`
+	// RawTx is the signed transaction, kept until the deposit is mined so
+	// that it can be rebroadcast with the same nonce after a crash.
+	RawTx string `json:"raw_tx,omitempty"`
+}
+
+type stateUpdate struct {
+	root   string
+	record depositRecord
+	done   chan error
+}
+
+// depositState persists the outcome of every deposit so that an interrupted
+// batch can be resumed. Records are keyed by deposit_data_root, which is unique
+// per deposit. The file is guarded by an exclusive lock for the lifetime of the
+// process, and all writes go through a single goroutine.
+type depositState struct {
+	path    string
+	lock    *os.File
+	mu      sync.Mutex
+	records map[string]depositRecord
+	updates chan stateUpdate
+	stopped chan struct{}
+}
+
+func openState(path string) (*depositState, error) {
+	lock, err := lockFile(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]depositRecord)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &records); err != nil {
+			unlockFile(lock)
+			return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		unlockFile(lock)
+		return nil, fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+
+	s := &depositState{
+		path:    path,
+		lock:    lock,
+		records: records,
+		updates: make(chan stateUpdate),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *depositState) Get(root string) (depositRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[root]
+	return record, ok
+}
+
+// Record stores the record and returns once it has been flushed to disk.
+func (s *depositState) Record(root string, record depositRecord) error {
+	done := make(chan error, 1)
+	s.updates <- stateUpdate{root: root, record: record, done: done}
+	return <-done
+}
+
+// skipConfirmed drops the deposits the state records as confirmed.
+func skipConfirmed(deposits []DepositData, state *depositState) []DepositData {
+	var remaining []DepositData
+	for _, data := range deposits {
+		if record, ok := state.Get(data.DepositDataRoot.String()); ok && isMined(record.Status) {
+			fmt.Printf("Deposit %s already confirmed in %s, skipping\n", record.TxHash, state.path)
+			continue
+		}
+		remaining = append(remaining, data)
+	}
+	return remaining
+}
+
+func (s *depositState) Close() error {
+	close(s.updates)
+	<-s.stopped
+	return unlockFile(s.lock)
+}
+
+func (s *depositState) run() {
+	defer close(s.stopped)
+	for update := range s.updates {
+		s.mu.Lock()
+		s.records[update.root] = update.record
+		data, err := json.MarshalIndent(s.records, "", "  ")
+		s.mu.Unlock()
+
+		if err == nil {
+			err = writeFileAtomic(s.path, data)
+		}
+		update.done <- err
+	}
+}
+
+// writeFileAtomic replaces path with data so that a crash never leaves a
+// partially written file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// restart closes state as a crashed run leaves it and opens it for the next
+// run, with a new Submitter of the test account.
+func restart(t *testing.T, node *fakeNode, state *depositState) (*Submitter, *depositState) {
+	t.Helper()
+	if err := state.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := openState(state.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reopened.Close() })
+	submitter := node.submitter(t, nil)
+	submitter.state = reopened
+	return submitter, reopened
+}
+
+func openTestState(t *testing.T, node *fakeNode) (*Submitter, *depositState) {
+	t.Helper()
+	state, err := openState(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	submitter := node.submitter(t, nil)
+	submitter.state = state
+	return submitter, state
+}
+
+func TestResumeAfterCrash(t *testing.T) {
+	data := testDepositData(t, 0x11, 32_000_000_000)
+	root := data.DepositDataRoot.String()
+
+	t.Run("signed, not broadcast", func(t *testing.T) {
+		node := newFakeNode(t, holeskyChainID)
+		submitter, state := openTestState(t, node)
+		// The node refusing the transaction stands in for a crash between
+		// signing and broadcasting
+		node.sendError = "connection refused"
+		if _, err := submitter.Submit(data); err == nil {
+			t.Fatal("the send did not fail")
+		}
+		node.sendError = ""
+		signed, _ := state.Get(root)
+		if signed.Status != statusSigned || signed.RawTx == "" {
+			t.Fatalf("record %+v, want a signed transaction", signed)
+		}
+
+		submitter, state = restart(t, node, state)
+		record, _ := state.Get(root)
+		tx, err := submitter.Resume(data, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx == nil || tx.Hash().Hex() != signed.TxHash || tx.Nonce() != 0 {
+			t.Fatalf("resumed %v, want %s rebroadcast with nonce 0", tx, signed.TxHash)
+		}
+		if sent := node.Sent(); len(sent) != 1 || sent[0].Hash() != tx.Hash() {
+			t.Errorf("node has %d transactions, want the signed one", len(sent))
+		}
+		if record, _ := state.Get(root); record.Status != statusBroadcast {
+			t.Errorf("status %q after the rebroadcast, want %q", record.Status, statusBroadcast)
+		}
+	})
+
+	t.Run("broadcast", func(t *testing.T) {
+		node := newFakeNode(t, holeskyChainID)
+		node.neverMine = true
+		submitter, state := openTestState(t, node)
+		sent, err := submitter.Submit(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		submitter, state = restart(t, node, state)
+		record, _ := state.Get(root)
+		if record.Status != statusBroadcast {
+			t.Fatalf("status %q, want %q", record.Status, statusBroadcast)
+		}
+		tx, err := submitter.Resume(data, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx == nil || tx.Hash() != sent.Hash() {
+			t.Fatalf("resumed %v, want %s", tx, sent.Hash().Hex())
+		}
+		if calls := node.Calls("eth_sendRawTransaction"); calls != 1 {
+			t.Errorf("%d sends, a transaction the node knows is polled, not sent again", calls)
+		}
+		// The next deposit takes the nonce after the resumed one
+		next, err := submitter.Submit(testDepositData(t, 0x22, 32_000_000_000))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.Nonce() != 1 {
+			t.Errorf("next deposit has nonce %d, want 1", next.Nonce())
+		}
+	})
+
+	t.Run("nonce used by another transaction", func(t *testing.T) {
+		node := newFakeNode(t, holeskyChainID)
+		submitter, state := openTestState(t, node)
+		node.sendError = "connection refused"
+		submitter.Submit(data)
+		node.sendError = ""
+		// Another transaction of the account took nonce 0 meanwhile
+		node.nonce = 1
+
+		submitter, state = restart(t, node, state)
+		record, _ := state.Get(root)
+		tx, err := submitter.Resume(data, record)
+		if err != nil || tx != nil {
+			t.Fatalf("resumed %v (%v), want nothing so that the deposit is submitted again", tx, err)
+		}
+		if tx, err = submitter.Submit(data); err != nil {
+			t.Fatal(err)
+		}
+		if tx.Nonce() != 1 || len(node.Sent()) != 1 {
+			t.Errorf("submitted again with nonce %d, %d sent, want nonce 1 and one", tx.Nonce(), len(node.Sent()))
+		}
+	})
+
+	t.Run("mined", func(t *testing.T) {
+		node := newFakeNode(t, holeskyChainID)
+		_, state := openTestState(t, node)
+		if err := state.Record(root, depositRecord{Index: data.index, PubKey: data.PubKey.String(), Status: statusConfirmed}); err != nil {
+			t.Fatal(err)
+		}
+		_, state = restart(t, node, state)
+		other := testDepositData(t, 0x22, 32_000_000_000)
+		remaining := skipConfirmed([]DepositData{data, other}, state)
+		if len(remaining) != 1 || remaining[0].PubKey.String() != other.PubKey.String() {
+			t.Errorf("%d deposits remain, want only the unconfirmed one", len(remaining))
+		}
+	})
+}
+
+func TestStateFileLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := openState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer state.Close()
+	if second, err := openState(path); err == nil {
+		second.Close()
+		t.Fatal("a second run opened the state file in use")
+	}
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Submitter builds, signs and sends deposit transactions.
+//
+// OnBeforeSubmit runs before a deposit is built and OnAfterSubmit once its
+// receipt, or the error fetching it, is known. Hook errors are logged and
+// only abort the run if Config.HookErrorsFatal is set.
+type Submitter struct {
+	OnBeforeSubmit func(data DepositData) error
+	OnAfterSubmit  func(data DepositData, receipt *types.Receipt, err error) error
+
+	cfg     Config
+	call    depositCall
+	client  *ethclient.Client
+	signer  TxSigner
+	from    common.Address
+	chainID *big.Int
+	txType  string
+	profile depositProfile
+	state   *depositState
+
+	// gasEstimates holds the gas limits found by EstimateAll by entry index.
+	gasEstimates map[int]uint64
+
+	// bundle holds the signed transactions of --tx-send-bundle until SendBundle.
+	bundle []bundledTx
+
+	nonces *NonceManager
+
+	// lastConfirmed is the deposit confirmed last, for --confirm-each-with-diff.
+	lastConfirmed *confirmedEntry
+	// lastBroadcast is when the last transaction was sent and paced the time
+	// --chunk-delay waited in total.
+	lastBroadcast time.Time
+	paced         time.Duration
+}
+
+func NewSubmitter(cfg Config, call depositCall, client *ethclient.Client, signer TxSigner, chainID *big.Int, txType string, profile depositProfile, state *depositState) *Submitter {
+	return &Submitter{
+		cfg:     cfg,
+		call:    call,
+		client:  client,
+		signer:  signer,
+		from:    signer.Address(),
+		chainID: chainID,
+		txType:  txType,
+		profile: profile,
+		state:   state,
+		nonces:  NewNonceManager(client, signer.Address(), cfg.NonceSource),
+	}
+}
+
+// WipeKey zeroes the signing key, or closes the HSM session. The Submitter
+// cannot sign afterwards.
+func (s *Submitter) WipeKey() {
+	s.signer.Close()
+	s.cfg.PrivateKey = ""
+	s.cfg.PKCS11Pin = ""
+}
+
+// AfterSubmit runs the OnAfterSubmit hook, if any.
+func (s *Submitter) AfterSubmit(data DepositData, receipt *types.Receipt, err error) {
+	if s.OnAfterSubmit != nil {
+		s.hookFailed("OnAfterSubmit", s.OnAfterSubmit(data, receipt, err))
+	}
+}
+
+func (s *Submitter) hookFailed(name string, err error) {
+	if err == nil {
+		return
+	}
+	if s.cfg.HookErrorsFatal {
+		log.Fatalf("%s hook failed: %v", name, err)
+	}
+	log.Printf("Warning: %s hook failed: %v", name, err)
+}
+
+// record stores the phase of a deposit in the state file, if there is one.
+func (s *Submitter) record(data DepositData, status string, tx *types.Transaction) {
+	if s.state == nil {
+		return
+	}
+	record := depositRecord{Index: data.index, PubKey: data.PubKey.String(), Status: status}
+	if tx != nil {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			log.Fatalf("Failed to encode transaction: %v", err)
+		}
+		record.TxHash = tx.Hash().Hex()
+		record.RawTx = hexutil.Encode(raw)
+	}
+	if err := s.state.Record(data.DepositDataRoot.String(), record); err != nil {
+		log.Fatalf("Failed to write state file: %v", err)
+	}
+}
+
+// printNonces prints the nonce the next transaction of the account will use
+// and the nonce after txs more transactions, for coordinating with other
+// tools sending from the same account.
+func (s *Submitter) printNonces(ctx context.Context, txs int) {
+	first, err := s.nonces.Peek(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get nonce: %v", err)
+	}
+	fmt.Printf("Account %s: next nonce %d (%s), nonce after %d transactions %d\n", s.from.Hex(), first, s.cfg.NonceSource, txs, first+uint64(txs))
+}
+
+// recordAll records the same phase for every deposit of one transaction.
+func (s *Submitter) recordAll(deposits []DepositData, status string, tx *types.Transaction) {
+	for _, data := range deposits {
+		s.record(data, status, tx)
+	}
+}
+
+// Submit asks the operator to confirm the deposit, then signs and sends it.
+// An error means nothing was sent: a *sendError if the deposit could not be
+// sent, else the entry itself is invalid.
+func (s *Submitter) Submit(data DepositData) (*types.Transaction, error) {
+	if err := data.checkFieldLengths(); err != nil {
+		return nil, err
+	}
+
+	// Pack the arguments
+	packedData, err := s.call.Pack(data.PubKey, data.WithdrawalCredentials, data.Signature, data.root())
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack arguments: %w", err)
+	}
+	value, err := s.depositValue(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.send([]DepositData{data}, packedData, value, s.gasLimit(data))
+}
+
+// sendError is an error of send: the deposits are valid but nothing was sent
+// for them, and the run stops. network is set if a call to the node failed,
+// so that the deposits can be submitted again.
+type sendError struct {
+	err     error
+	network bool
+}
+
+func (e *sendError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sendError) Unwrap() error {
+	return e.err
+}
+
+func notSent(format string, args ...any) error {
+	return &sendError{err: fmt.Errorf(format, args...)}
+}
+
+func networkFailure(format string, args ...any) error {
+	return &sendError{err: fmt.Errorf(format, args...), network: true}
+}
+
+// errCancelled is the sendError of a transaction the operator did not confirm.
+var errCancelled = errors.New("transaction cancelled")
+
+// send runs the OnBeforeSubmit hook for deposits, then asks the operator to
+// confirm a transaction carrying all of them, signs and sends it. Its errors
+// are *sendError.
+func (s *Submitter) send(deposits []DepositData, packedData []byte, amountWei *big.Int, gasLimit uint64) (*types.Transaction, error) {
+	client, fromAddress, chainID := s.client, s.from, s.chainID
+
+	if err := checkDeadline(context.Background(), client, s.cfg.ValidUntilBlock); err != nil {
+		return nil, &sendError{err: err}
+	}
+
+	for _, data := range deposits {
+		if s.OnBeforeSubmit != nil {
+			s.hookFailed("OnBeforeSubmit", s.OnBeforeSubmit(data))
+		}
+		s.record(data, statusBuilding, nil)
+	}
+
+	nonce, err := s.nonces.Next(context.Background())
+	if err != nil {
+		return nil, networkFailure("failed to get nonce: %w", err)
+	}
+
+	tipCap, feeCap, err := s.fees(deposits, gasLimit)
+	if err != nil {
+		return nil, err
+	}
+	if s.cfg.InteractiveGas && !s.cfg.Yes {
+		tipCap, feeCap, err = s.approveFees(deposits, tipCap, feeCap, gasLimit)
+		if err != nil {
+			return nil, err
+		}
+		if feeCap == nil {
+			return nil, &sendError{err: errCancelled}
+		}
+	}
+
+	depositAddress := s.cfg.DepositAddress()
+
+	msg := ethereum.CallMsg{From: fromAddress, To: &depositAddress, Gas: gasLimit, Value: amountWei, Data: packedData}
+	if s.cfg.Simulate {
+		if err := s.simulate(context.Background(), msg); err != nil {
+			return nil, notSent("deposit simulation failed: %w", err)
+		}
+		fmt.Printf("Deposit simulation succeeded\n")
+	}
+
+	var accessList types.AccessList
+	if s.cfg.AccessList == accessListAuto {
+		accessList, err = createAccessList(context.Background(), client, msg)
+		if err != nil {
+			return nil, notSent("failed to create access list: %w", err)
+		}
+	}
+
+	build := func(nonce uint64) *types.Transaction {
+		if s.txType == txTypeLegacy {
+			if accessList != nil {
+				return types.NewTx(&types.AccessListTx{
+					ChainID:    chainID,
+					Nonce:      nonce,
+					GasPrice:   feeCap,
+					Gas:        gasLimit,
+					To:         &depositAddress,
+					Value:      amountWei,
+					Data:       packedData,
+					AccessList: accessList,
+				})
+			}
+			return types.NewTx(&types.LegacyTx{
+				Nonce:    nonce,
+				GasPrice: feeCap,
+				Gas:      gasLimit,
+				To:       &depositAddress,
+				Value:    amountWei,
+				Data:     packedData,
+			})
+		}
+
+		// Create EIP-1559 transaction
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    chainID,
+			Nonce:      nonce,
+			GasTipCap:  tipCap,
+			GasFeeCap:  feeCap,
+			Gas:        gasLimit,
+			To:         &depositAddress,
+			Value:      amountWei,
+			Data:       packedData,
+			AccessList: accessList,
+		})
+	}
+	tx := build(nonce)
+
+	if estimate, err := estimateInclusion(context.Background(), client, tx.GasTipCap(), tx.GasFeeCap()); err != nil {
+		log.Printf("Warning: failed to estimate the inclusion time: %v", err)
+	} else {
+		fmt.Printf("Inclusion: %s\n", estimate)
+	}
+
+	if !s.confirmTransaction(deposits, tx) {
+		return nil, &sendError{err: errCancelled}
+	}
+
+	signedTx, err := signTx(s.signer, tx, chainID)
+	if err != nil {
+		return nil, notSent("failed to sign transaction: %w", err)
+	}
+
+	s.recordAll(deposits, statusSigned, signedTx)
+	if s.cfg.SignOnly {
+		fmt.Printf("Transaction signed, not sent: %s\n", signedTx.Hash().Hex())
+		return signedTx, nil
+	}
+	if s.cfg.TxSendBundle != "" {
+		s.bundle = append(s.bundle, bundledTx{deposits: deposits, tx: signedTx})
+		fmt.Printf("Transaction signed for the bundle: %s\n", signedTx.Hash().Hex())
+		return signedTx, nil
+	}
+
+	s.pace()
+	err = client.SendTransaction(context.Background(), signedTx)
+	// Another process may have used the account since the nonce was fetched:
+	// pick up the new pending nonce and re-sign, but only a bounded number of times.
+	for attempt := 0; err != nil && isNonceTooLow(err) && attempt < maxNonceRecoveries; attempt++ {
+		if nonceErr := s.nonces.Refresh(context.Background()); nonceErr != nil {
+			return nil, networkFailure("failed to get nonce: %w", nonceErr)
+		}
+		newNonce, nonceErr := s.nonces.Next(context.Background())
+		if nonceErr != nil {
+			return nil, networkFailure("failed to get nonce: %w", nonceErr)
+		}
+		log.Printf("Nonce %d is too low, retrying with pending nonce %d", nonce, newNonce)
+
+		nonce = newNonce
+		signedTx, err = signTx(s.signer, build(nonce), chainID)
+		if err != nil {
+			return nil, notSent("failed to sign transaction: %w", err)
+		}
+		s.recordAll(deposits, statusSigned, signedTx)
+		err = client.SendTransaction(context.Background(), signedTx)
+	}
+	if err != nil {
+		return nil, networkFailure("failed to send transaction: %w", err)
+	}
+
+	s.recordAll(deposits, statusBroadcast, signedTx)
+
+	fmt.Printf("Transaction sent: %s\n", signedTx.Hash().Hex())
+	for _, data := range deposits {
+		slog.Debug("deposit sent", "pubkey", data.PubKey, "tx", signedTx.Hash().Hex(), "nonce", signedTx.Nonce())
+	}
+	return signedTx, nil
+}
+
+// fees returns the tip and fee caps of a transaction of deposits: the
+// configured ones, else those of the gas strategy or the node. tipCap is nil
+// for legacy transactions. A suggested tip above --warn-tip-gwei is warned
+// about. Its errors are *sendError.
+func (s *Submitter) fees(deposits []DepositData, gasLimit uint64) (tipCap, feeCap *big.Int, err error) {
+	client := s.client
+	// Take gas fees from the gas strategy unless configured explicitly, legacy
+	// transactions only use the fee cap as gas price, suggested by the node
+	tipCap, feeCap, err = depositFees(s.cfg, deposits)
+	if err != nil {
+		return nil, nil, notSent("invalid deposit fees: %w", err)
+	}
+	suggestedTip := tipCap == nil
+	if (tipCap == nil || feeCap == nil) && s.txType == txTypeDynamic {
+		strategyTip, strategyFee, err := gasStrategies[s.cfg.GasStrategy].fees(context.Background(), client)
+		if err != nil {
+			log.Printf("Warning: %s gas strategy failed, using the node's fee suggestions: %v", s.cfg.GasStrategy, err)
+			if tipCap == nil {
+				tipCap, err = client.SuggestGasTipCap(context.Background())
+				if err != nil {
+					return nil, nil, networkFailure("failed to get gas tip cap: %w", err)
+				}
+			}
+		} else {
+			if tipCap == nil {
+				tipCap = strategyTip
+			}
+			if feeCap == nil {
+				feeCap = strategyFee
+				if feeCap.Cmp(tipCap) < 0 {
+					feeCap = new(big.Int).Set(tipCap)
+				}
+			}
+		}
+	}
+
+	if feeCap == nil {
+		feeCap, err = client.SuggestGasPrice(context.Background())
+		if err != nil {
+			return nil, nil, networkFailure("failed to get gas fee cap: %w", err)
+		}
+		// A --gas-tip-cap above the suggested gas price would make the
+		// transaction invalid
+		if s.txType == txTypeDynamic && tipCap != nil && tipCap.Cmp(feeCap) > 0 {
+			feeCap = new(big.Int).Set(tipCap)
+		}
+	}
+	if suggestedTip && tipCap != nil {
+		s.warnHighTip(tipCap, gasLimit)
+	}
+	return tipCap, feeCap, nil
+}
+
+// warnHighTip warns loudly when tipCap is above --warn-tip-gwei, which is
+// easy to miss in the confirmation during a fee spike. It only informs, the
+// cap is --gas-tip-cap.
+func (s *Submitter) warnHighTip(tipCap *big.Int, gasLimit uint64) {
+	if s.cfg.WarnTip == nil || s.cfg.WarnTip.Sign() == 0 || tipCap.Cmp(s.cfg.WarnTip) <= 0 {
+		return
+	}
+	tips := new(big.Int).Mul(tipCap, new(big.Int).SetUint64(gasLimit))
+	log.Printf("WARNING: HIGH TIP: the suggested tip of %s gwei is above --warn-tip-gwei %s gwei, up to %s ETH in tips for %d gas; wait out the spike or set --gas-tip-cap",
+		formatWeiAsGwei(tipCap), formatWeiAsGwei(s.cfg.WarnTip), formatWeiAsETH(tips), gasLimit)
+}
+
+// pace waits until --chunk-delay passed since the last broadcast. Time spent
+// on confirmations and fees counts towards it.
+func (s *Submitter) pace() {
+	if s.cfg.ChunkDelay <= 0 {
+		return
+	}
+	if wait := s.cfg.ChunkDelay - time.Since(s.lastBroadcast); !s.lastBroadcast.IsZero() && wait > 0 {
+		time.Sleep(wait)
+		s.paced += wait
+	}
+	s.lastBroadcast = time.Now()
+}
+
+// Paced returns the time --chunk-delay added between broadcasts.
+func (s *Submitter) Paced() time.Duration {
+	return s.paced
+}
+
+// WaitForReceipt waits for signedTx to be mined. An error leaves the
+// transaction in flight, it was sent and may still be mined.
+func (s *Submitter) WaitForReceipt(signedTx *types.Transaction) (*types.Receipt, error) {
+	fmt.Printf("Waiting for the receipt of %s...\n\n", signedTx.Hash().Hex())
+
+	receipt, err := waitMined(context.Background(), s.client, signedTx, s.cfg.Poll())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the receipt of %s: %w", signedTx.Hash().Hex(), err)
+	}
+
+	// With --receipt-output-dir the receipt goes to its file instead
+	if s.cfg.ReceiptOutputDir != "" {
+		return receipt, nil
+	}
+	receiptJSON, err := json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to marshal receipt: %v", err)
+	}
+
+	fmt.Printf("Transaction receipt: %s\n", string(receiptJSON))
+	return receipt, nil
+}
+
+// Resume picks up a deposit that a previous run signed but may not have
+// broadcast. The stored transaction is rebroadcast with its original nonce
+// unless the node already knows it. It returns the transaction to wait for,
+// or nil if the nonce was consumed by another transaction and the deposit
+// has to be submitted again.
+func (s *Submitter) Resume(data DepositData, record depositRecord) (*types.Transaction, error) {
+	raw, err := hexutil.Decode(record.RawTx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw transaction in state file: %w", err)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("invalid raw transaction in state file: %w", err)
+	}
+
+	if _, _, err := s.client.TransactionByHash(context.Background(), tx.Hash()); err == nil {
+		s.nonces.Used(tx.Nonce())
+		fmt.Printf("Resuming %s deposit %s\n", record.Status, tx.Hash().Hex())
+		s.record(data, statusBroadcast, tx)
+		return tx, nil
+	}
+
+	if err := checkDeadline(context.Background(), s.client, s.cfg.ValidUntilBlock); err != nil {
+		return nil, fmt.Errorf("not rebroadcasting %s: %w", tx.Hash().Hex(), err)
+	}
+	err = s.client.SendTransaction(context.Background(), tx)
+	switch {
+	case err == nil || isAlreadyKnown(err):
+		s.nonces.Used(tx.Nonce())
+		fmt.Printf("Rebroadcast %s deposit %s with nonce %d\n", record.Status, tx.Hash().Hex(), tx.Nonce())
+		s.record(data, statusBroadcast, tx)
+		return tx, nil
+	case isNonceTooLow(err):
+		log.Printf("Nonce %d of %s deposit %s was used by another transaction, submitting it again", tx.Nonce(), record.Status, tx.Hash().Hex())
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("failed to rebroadcast %s: %w", tx.Hash().Hex(), err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestDepositDataRootLength(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	submitter := node.submitter(t, nil)
+	for _, n := range []int{31, 33} {
+		want := fmt.Sprintf("deposit_data_root is %d bytes, expected 32", n)
+
+		// Read from a file, the entry is reported by ValidateBatch naming its
+		// index
+		entry := testEntry(t, 0x11, 32_000_000_000)
+		entry["deposit_data_root"] = strings.Repeat("ab", n)
+		raw, _ := json.Marshal([]map[string]any{testEntry(t, 0x22, 32_000_000_000), entry})
+		deposits, err := decodeDeposits(raw, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if problems := ValidateBatch(deposits); len(problems) != 1 || problems[0].Index != 1 || problems[0].Err.Error() != want {
+			t.Errorf("%d byte root in a file: problems %v, want entry 1: %q", n, problems, want)
+		}
+
+		// Built by library users, Submit refuses it instead of truncating or
+		// panicking
+		data := testDepositData(t, 0x11, 32_000_000_000)
+		data.DepositDataRoot = make(HexBytes, n)
+		if _, err := submitter.Submit(data); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%d byte root submitted: error %v, want %q", n, err, want)
+		}
+	}
+	if sent := node.Sent(); len(sent) != 0 {
+		t.Errorf("%d transactions sent", len(sent))
+	}
+}
+
+func TestFeesWithoutGasStrategy(t *testing.T) {
+	node := newFakeNode(t, holeskyChainID)
+	node.noFeeHistory = true
+	cfg := DefaultConfig()
+	// Above the 2 gwei eth_gasPrice of the node
+	cfg.GasTipCap = big.NewInt(5_000_000_000)
+	submitter := node.submitter(t, &cfg)
+
+	tipCap, feeCap, err := submitter.fees(nil, cfg.GasLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tipCap.Cmp(cfg.GasTipCap) != 0 || feeCap.Cmp(tipCap) != 0 {
+		t.Errorf("tip cap %s and fee cap %s wei, want both %s", tipCap, feeCap, cfg.GasTipCap)
+	}
+
+	// A tip below the suggestion keeps the suggested fee cap
+	cfg.GasTipCap = big.NewInt(1_000_000_000)
+	submitter = node.submitter(t, &cfg)
+	if _, feeCap, err = submitter.fees(nil, cfg.GasLimit); err != nil || feeCap.Int64() != 2_000_000_000 {
+		t.Errorf("fee cap %s (%v), want the suggested 2000000000", feeCap, err)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Final states of a deposit file entry, in the order the summary sorts them
+// by with --summary-sort status.
+const (
+	outcomeConfirmed        = "confirmed"
+	outcomeUnverified       = "unverified"
+	outcomeReverted         = "reverted"
+	outcomeFailedValidation = "failed (validation)"
+	outcomeFailedNetwork    = "failed (network)"
+	// outcomeInFlight is a deposit that was sent but whose receipt could not
+	// be fetched: it may still be mined and must not be sent again blindly.
+	outcomeInFlight = "in flight"
+	outcomeSkipped  = "skipped"
+)
+
+var outcomeOrder = []string{outcomeConfirmed, outcomeUnverified, outcomeReverted, outcomeFailedValidation, outcomeFailedNetwork, outcomeInFlight, outcomeSkipped}
+
+const (
+	summarySortIndex  = "index"
+	summarySortStatus = "status"
+)
+
+// Result is the outcome of one entry of the deposit file. A run has one for
+// every entry, also for those skipped or failed before anything was sent;
+// the summary table and --summary-file are formatted from them.
+type Result struct {
+	// Index is the zero-based position of the entry in the deposit file.
+	Index  int
+	PubKey string
+	// TxHash, BlockNumber and GasUsed are those of the transaction carrying
+	// the deposit, zero until it was sent or mined. A batch transaction
+	// reports its gas for each of its deposits.
+	TxHash      common.Hash
+	BlockNumber uint64
+	GasUsed     uint64
+	// Status is one of the outcome constants.
+	Status string
+	// Detail complements Status, such as the deposit index of a verified
+	// deposit or why an entry was skipped.
+	Detail string
+	// Err is why the entry did not succeed, nil for confirmed and skipped
+	// entries.
+	Err error
+
+	amount *big.Int
+}
+
+// entryOutcome is a Result as written to --summary-file.
+type entryOutcome struct {
+	Index   int    `json:"index"`
+	PubKey  string `json:"pubkey"`
+	Status  string `json:"status"`
+	TxHash  string `json:"tx_hash,omitempty"`
+	Block   uint64 `json:"block,omitempty"`
+	GasUsed uint64 `json:"gas_used,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+
+	amount *big.Int
+}
+
+func (r Result) outcome() entryOutcome {
+	e := entryOutcome{Index: r.Index, PubKey: r.PubKey, Status: r.Status, Block: r.BlockNumber, GasUsed: r.GasUsed, Detail: r.Detail, amount: r.amount}
+	if r.TxHash != (common.Hash{}) {
+		e.TxHash = r.TxHash.Hex()
+	}
+	if e.Detail == "" && r.Err != nil {
+		e.Detail = r.Err.Error()
+	}
+	return e
+}
+
+// runSummary collects the result of every entry of a run. It is safe for
+// concurrent use, so that receipts can be recorded as they come in.
+type runSummary struct {
+	mu      sync.Mutex
+	started time.Time
+	entries []Result
+	// mined holds the gas of every mined transaction, a batch transaction
+	// is mined once for each of its deposits.
+	mined map[common.Hash]minedGas
+}
+
+func newRunSummary() *runSummary {
+	return &runSummary{started: time.Now(), mined: make(map[common.Hash]minedGas)}
+}
+
+func (r *runSummary) add(result Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, result)
+}
+
+func newResult(data DepositData, status string) Result {
+	return Result{Index: data.index, PubKey: data.PubKey.String(), Status: status, amount: &data.Amount}
+}
+
+// Carry records the outcome of an entry in an earlier run.
+func (r *runSummary) Carry(e entryOutcome) {
+	result := Result{
+		Index:       e.Index,
+		PubKey:      e.PubKey,
+		Status:      e.Status,
+		BlockNumber: e.Block,
+		GasUsed:     e.GasUsed,
+		Detail:      e.Detail,
+		amount:      e.amount,
+	}
+	if e.TxHash != "" {
+		result.TxHash = common.HexToHash(e.TxHash)
+	}
+	if e.Status == outcomeUnverified || e.Status == outcomeInFlight {
+		result.Err = errors.New(e.Detail)
+	}
+	r.add(result)
+}
+
+// Skipped records the entries of before that are missing from after.
+func (r *runSummary) Skipped(before, after []DepositData, reason string) {
+	kept := make(map[int]bool, len(after))
+	for _, data := range after {
+		kept[data.index] = true
+	}
+	for _, data := range before {
+		if !kept[data.index] {
+			result := newResult(data, outcomeSkipped)
+			result.Detail = reason
+			r.add(result)
+		}
+	}
+}
+
+// Mined records a mined deposit with its state file status.
+func (r *runSummary) Mined(data DepositData, status string, receipt *types.Receipt, detail string) {
+	result := newResult(data, outcomeConfirmed)
+	result.TxHash, result.BlockNumber, result.GasUsed, result.Detail = receipt.TxHash, receipt.BlockNumber.Uint64(), receipt.GasUsed, detail
+	switch status {
+	case statusReverted:
+		result.Status, result.Err = outcomeReverted, errors.New("transaction reverted")
+		if detail != "" {
+			result.Err = errors.New("reverted: " + detail)
+		}
+	case statusUnverified:
+		result.Status, result.Err = outcomeUnverified, errors.New(detail)
+	}
+	r.add(result)
+
+	if receipt.EffectiveGasPrice != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.mined[receipt.TxHash] = minedGas{used: receipt.GasUsed, price: receipt.EffectiveGasPrice}
+	}
+}
+
+// Failed records an entry that did not make it on-chain, or whose outcome
+// is unknown, with the hash of its transaction if one was sent.
+func (r *runSummary) Failed(data DepositData, status string, txHash common.Hash, err error) {
+	result := newResult(data, status)
+	result.TxHash, result.Err = txHash, err
+	r.add(result)
+}
+
+// Counts returns the number of attempted, succeeded and failed entries, and
+// the gwei deposited by the successful ones.
+func (r *runSummary) Counts() (total, succeeded, failed int, gwei *big.Int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	gwei = new(big.Int)
+	for _, e := range r.entries {
+		switch e.Status {
+		case outcomeSkipped:
+			continue
+		case outcomeConfirmed:
+			succeeded++
+			gwei.Add(gwei, e.amount)
+		default:
+			failed++
+		}
+		total++
+	}
+	return total, succeeded, failed, gwei
+}
+
+// Fees returns the summed fees in wei of the mined transactions, reverted
+// ones included.
+func (r *runSummary) Fees() *big.Int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fees := new(big.Int)
+	for _, gas := range r.mined {
+		fees.Add(fees, gas.fee())
+	}
+	return fees
+}
+
+// Results returns the result of every entry in file order.
+func (r *runSummary) Results() []Result {
+	return r.Sorted(summarySortIndex)
+}
+
+// Sorted returns the results ordered by file index or by status.
+func (r *runSummary) Sorted(by string) []Result {
+	r.mu.Lock()
+	entries := append([]Result(nil), r.entries...)
+	r.mu.Unlock()
+	rank := make(map[string]int, len(outcomeOrder))
+	for i, status := range outcomeOrder {
+		rank[status] = i
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if by == summarySortStatus && entries[i].Status != entries[j].Status {
+			return rank[entries[i].Status] < rank[entries[j].Status]
+		}
+		return entries[i].Index < entries[j].Index
+	})
+	return entries
+}
+
+// Print writes the summary table.
+func (r *runSummary) Print(w io.Writer, by string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tPUBKEY\tSTATUS\tTX\tBLOCK\tDETAIL")
+	for _, result := range r.Sorted(by) {
+		e := result.outcome()
+		block := ""
+		if e.Block != 0 {
+			block = fmt.Sprint(e.Block)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", e.Index, shortPubkey(e.PubKey), e.Status, e.TxHash, block, e.Detail)
+	}
+	tw.Flush()
+
+	_, succeeded, _, gwei := r.Counts()
+	fmt.Fprintf(w, "\nDeposited %s ETH in %d deposits, fees %s ETH\n", formatGweiAsETH(gwei), succeeded, formatWeiAsETH(r.Fees()))
+}
+
+// Write stores the summary as JSON, an array of the entries, or with
+// withGas an object of the entries and the gas report.
+func (r *runSummary) Write(path, by string, withGas bool) error {
+	results := r.Sorted(by)
+	outcomes := make([]entryOutcome, len(results))
+	for i, result := range results {
+		outcomes[i] = result.outcome()
+	}
+	var doc any = outcomes
+	if withGas {
+		doc = reportWithGas{Entries: outcomes, GasReport: r.GasReport()}
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TestRunSummaryConcurrent records receipts from many goroutines, as
+// parallel submissions do; run it with -race.
+func TestRunSummaryConcurrent(t *testing.T) {
+	const entries = 64
+	summary := newRunSummary()
+	var wg sync.WaitGroup
+	for i := range entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := DepositData{PubKey: bytes.Repeat([]byte{byte(i)}, pubkeyLength), index: i}
+			data.Amount.SetUint64(1_000_000_000)
+			// Every two entries share a batch transaction, the second of four
+			// failing before it was sent
+			txHash := common.BigToHash(big.NewInt(int64(i / 2)))
+			if i%4 == 3 {
+				summary.Failed(data, outcomeFailedNetwork, common.Hash{}, errors.New("connection refused"))
+			} else {
+				receipt := &types.Receipt{TxHash: txHash, BlockNumber: big.NewInt(int64(100 + i)), GasUsed: 50_000, EffectiveGasPrice: big.NewInt(2_000_000_000)}
+				summary.Mined(data, statusConfirmed, receipt, "")
+			}
+			// Readers run while results come in
+			summary.Counts()
+			summary.Fees()
+		}()
+	}
+	wg.Wait()
+
+	total, succeeded, failed, gwei := summary.Counts()
+	if total != entries || succeeded != 48 || failed != 16 {
+		t.Errorf("counted %d entries, %d succeeded and %d failed, want 64, 48 and 16", total, succeeded, failed)
+	}
+	if gwei.Cmp(big.NewInt(48_000_000_000)) != 0 {
+		t.Errorf("deposited %s gwei, want 48000000000", gwei)
+	}
+	// 32 transactions, each counted once however many deposits it carried
+	if want := big.NewInt(32 * 50_000 * 2_000_000_000); summary.Fees().Cmp(want) != 0 {
+		t.Errorf("fees %s wei, want %s", summary.Fees(), want)
+	}
+
+	for i, result := range summary.Results() {
+		if result.Index != i {
+			t.Fatalf("result %d has index %d, want file order", i, result.Index)
+		}
+	}
+	var first, second strings.Builder
+	summary.Print(&first, summarySortStatus)
+	summary.Print(&second, summarySortStatus)
+	if first.String() != second.String() {
+		t.Error("the summary printed differently twice")
+	}
+	if !strings.HasSuffix(first.String(), "\nDeposited 48 ETH in 48 deposits, fees 0.0032 ETH\n") {
+		t.Errorf("summary ends with %q", first.String()[strings.LastIndex(strings.TrimSuffix(first.String(), "\n"), "\n"):])
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+)
+
+const templateNotes = `Notes:
+  - The file holds a JSON array of deposits; a single deposit may also be a
+    JSON object on its own, as written by ethdo.
+  - amount is a number in GWEI, not ETH: 32000000000 = 32 ETH, 1000000000 = 1 ETH.
+    It may also be a string with a unit instead: "32 ETH", "32000000000 gwei".
+  - pubkey (48 bytes), withdrawal_credentials (32 bytes), signature (96 bytes)
+    and deposit_data_root (32 bytes) are hex strings, with or without the 0x prefix.
+  - gas_fee_cap_gwei and gas_tip_cap_gwei are optional and override the fees of one deposit.
+  - Use staking-deposit-cli to produce real values; the zeros below are placeholders.`
+
+// templateEntry returns a placeholder deposit with correctly sized fields.
+func templateEntry() DepositData {
+	return DepositData{
+		Amount:                *big.NewInt(32000000000),
+		PubKey:                make(HexBytes, pubkeyLength),
+		WithdrawalCredentials: make(HexBytes, withdrawalCredentialsLength),
+		Signature:             make(HexBytes, signatureLength),
+		DepositDataRoot:       make(HexBytes, rootLength),
+	}
+}
+
+func printTemplate() {
+	single, err := json.MarshalIndent([]DepositData{templateEntry()}, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to marshal template: %v", err)
+	}
+
+	batch, err := json.MarshalIndent([]DepositData{templateEntry(), templateEntry()}, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to marshal template: %v", err)
+	}
+
+	fmt.Printf("%s\n\n", templateNotes)
+	fmt.Printf("Single deposit:\n%s\n\n", string(single))
+	fmt.Printf("Batch of deposits:\n%s\n", string(batch))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// txpoolContent is the reply of txpool_contentFrom, transactions keyed by
+// nonce. Only their presence matters here.
+type txpoolContent struct {
+	Pending map[string]any `json:"pending"`
+	Queued  map[string]any `json:"queued"`
+}
+
+type txpoolStatus struct {
+	Pending hexutil.Uint64 `json:"pending"`
+	Queued  hexutil.Uint64 `json:"queued"`
+}
+
+// txpoolReporter prints where a sent transaction sits in the node's
+// transaction pool. Providers often do not offer the txpool namespace, the
+// first failure turns the reporter off.
+type txpoolReporter struct {
+	client   *ethclient.Client
+	from     common.Address
+	disabled bool
+}
+
+// Report prints whether tx is pending, i.e. executable, or queued behind a
+// nonce gap, how many transactions of the sender are ahead of it and the
+// size of the whole pool.
+func (t *txpoolReporter) Report(ctx context.Context, tx *types.Transaction) {
+	if t == nil || t.disabled {
+		return
+	}
+	var content txpoolContent
+	if err := t.client.Client().CallContext(ctx, &content, "txpool_contentFrom", t.from); err != nil {
+		log.Printf("Warning: txpool status is not available from this node: %v", err)
+		t.disabled = true
+		return
+	}
+
+	state, others := "not in the pool (mined or dropped)", content.Pending
+	if _, ok := content.Pending[strconv.FormatUint(tx.Nonce(), 10)]; ok {
+		state = "pending"
+	} else if _, ok := content.Queued[strconv.FormatUint(tx.Nonce(), 10)]; ok {
+		state, others = "queued, waiting for a lower nonce", content.Queued
+	}
+	ahead := 0
+	for nonce := range others {
+		if n, err := strconv.ParseUint(nonce, 10, 64); err == nil && n < tx.Nonce() {
+			ahead++
+		}
+	}
+	line := fmt.Sprintf("Txpool: %s is %s, %d of your transactions ahead", tx.Hash().Hex(), state, ahead)
+
+	var status txpoolStatus
+	if err := t.client.Client().CallContext(ctx, &status, "txpool_status"); err == nil {
+		line += fmt.Sprintf(", node pool has %d pending and %d queued", status.Pending, status.Queued)
+	}
+	fmt.Println(line)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Values of --tx-type.
+const (
+	txTypeAuto    = "auto"
+	txTypeDynamic = "dynamic"
+	txTypeLegacy  = "legacy"
+)
+
+// resolveTxType picks the transaction type for the run. EIP-1559 support is
+// detected from the presence of a base fee in the latest header.
+func resolveTxType(ctx context.Context, client *ethclient.Client, requested string) (string, error) {
+	if requested == txTypeLegacy {
+		return txTypeLegacy, nil
+	}
+
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest header: %w", err)
+	}
+	supports1559 := header.BaseFee != nil
+
+	switch {
+	case supports1559:
+		return txTypeDynamic, nil
+	case requested == txTypeDynamic:
+		return "", errors.New("the latest block has no base fee, the chain does not support EIP-1559 transactions")
+	default:
+		return txTypeLegacy, nil
+	}
+}
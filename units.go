@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+var (
+	gweiPerETH = big.NewInt(1e9)
+	weiPerETH  = big.NewInt(1e18)
+)
+
+// amountUnits are the units of amount strings such as "32 ETH", in wei.
+var amountUnits = map[string]*big.Int{
+	"wei":   big.NewInt(1),
+	"gwei":  weiPerGwei,
+	"eth":   weiPerETH,
+	"ether": weiPerETH,
+}
+
+// parseAmount parses an amount with a unit, such as "32 ETH", "1.5 ether" or
+// "32000000000 gwei", into gwei. Units are case-insensitive, the amount must
+// be a whole number of gwei.
+func parseAmount(s string) (*big.Int, error) {
+	i := strings.IndexFunc(s, unicode.IsLetter)
+	if i < 0 {
+		return nil, fmt.Errorf("invalid amount %q, expected a number and a unit such as \"32 ETH\"", s)
+	}
+	number, unitName := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i:])
+	unit, ok := amountUnits[strings.ToLower(unitName)]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q in amount %q, use wei, gwei or ETH", unitName, s)
+	}
+	value, ok := new(big.Rat).SetString(number)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid number %q in amount %q", number, s)
+	}
+	gwei := value.Mul(value, new(big.Rat).SetFrac(unit, weiPerGwei))
+	if !gwei.IsInt() {
+		return nil, fmt.Errorf("amount %q is not a whole number of gwei", s)
+	}
+	return new(big.Int).Set(gwei.Num()), nil
+}
+
+// formatGweiAsETH renders a gwei amount in ETH without trailing zeros.
+func formatGweiAsETH(gwei *big.Int) string {
+	s := new(big.Rat).SetFrac(gwei, gweiPerETH).FloatString(9)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// formatWeiAsETH renders a wei amount in ETH with at most 6 decimals.
+func formatWeiAsETH(wei *big.Int) string {
+	s := new(big.Rat).SetFrac(wei, weiPerETH).FloatString(6)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// formatWeiAsGwei renders a wei amount in gwei without trailing zeros.
+func formatWeiAsGwei(wei *big.Int) string {
+	s := new(big.Rat).SetFrac(wei, weiPerGwei).FloatString(9)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// totalGwei sums the amounts of deposits.
+func totalGwei(deposits []DepositData) *big.Int {
+	total := new(big.Int)
+	for i := range deposits {
+		total.Add(total, &deposits[i].Amount)
+	}
+	return total
+}
+
+// ethLikeAmounts returns the deposits whose amount is at most threshold
+// gwei, which most likely means the amount was written in ETH.
+func ethLikeAmounts(deposits []DepositData, threshold uint64) []DepositData {
+	var suspicious []DepositData
+	limit := new(big.Int).SetUint64(threshold)
+	for _, data := range deposits {
+		if data.Amount.Cmp(limit) <= 0 {
+			suspicious = append(suspicious, data)
+		}
+	}
+	return suspicious
+}
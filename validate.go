@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Deposit amount bounds in gwei: the deposit contract rejects less than
+// 1 ETH, and more than the 2048 ETH maximum effective balance of a
+// compounding validator never counts.
+var (
+	minDepositGwei = big.NewInt(1_000_000_000)
+	maxDepositGwei = big.NewInt(2048_000_000_000)
+)
+
+// Withdrawal credential prefixes: a BLS withdrawal key, an execution address
+// and an execution address of a compounding validator.
+const (
+	blsWithdrawalPrefix         = 0x00
+	eth1AddressWithdrawalPrefix = 0x01
+	compoundingWithdrawalPrefix = 0x02
+)
+
+// EntryError is a problem of one entry of the deposit file.
+type EntryError struct {
+	Index  int
+	PubKey string
+	Err    error
+}
+
+func (e EntryError) Error() string {
+	return fmt.Sprintf("entry %d (%s): %v", e.Index, shortPubkey(e.PubKey), e.Err)
+}
+
+// ValidateBatch runs every offline check on every entry and returns all
+// problems found, in file order, instead of stopping at the first: field
+// lengths, the deposit data root, the withdrawal credential prefix, the
+// amount bounds and repeated entries.
+func ValidateBatch(deposits []DepositData) []EntryError {
+	var problems []EntryError
+	for _, data := range deposits {
+		for _, err := range validateEntry(data) {
+			problems = append(problems, EntryError{Index: data.index, PubKey: data.PubKey.String(), Err: err})
+		}
+	}
+	_, duplicates := dedupeDeposits(deposits)
+	for _, d := range duplicates {
+		problems = append(problems, EntryError{
+			Index:  d.Index,
+			PubKey: d.PubKey,
+			Err:    fmt.Errorf("repeats the pubkey and amount of entry %d", d.FirstIndex),
+		})
+	}
+	return problems
+}
+
+// dropInvalid splits deposits into the entries without problems and those
+// with one, whose problems are returned joined by index.
+func dropInvalid(deposits []DepositData, problems []EntryError) (valid, dropped []DepositData, errs map[int]error) {
+	byIndex := make(map[int][]error)
+	for _, p := range problems {
+		byIndex[p.Index] = append(byIndex[p.Index], p.Err)
+	}
+	errs = make(map[int]error, len(byIndex))
+	for _, data := range deposits {
+		if entryErrs, ok := byIndex[data.index]; ok {
+			dropped = append(dropped, data)
+			errs[data.index] = errors.Join(entryErrs...)
+		} else {
+			valid = append(valid, data)
+		}
+	}
+	return valid, dropped, errs
+}
+
+func validateEntry(data DepositData) []error {
+	var errs []error
+	if data.Amount.Cmp(minDepositGwei) < 0 {
+		errs = append(errs, fmt.Errorf("amount %s gwei is below the minimum deposit of %s ETH", data.Amount.String(), formatGweiAsETH(minDepositGwei)))
+	} else if data.Amount.Cmp(maxDepositGwei) > 0 {
+		errs = append(errs, fmt.Errorf("amount %s gwei is above the maximum effective balance of %s ETH", data.Amount.String(), formatGweiAsETH(maxDepositGwei)))
+	}
+
+	if err := data.checkFieldLengths(); err != nil {
+		return append(errs, err)
+	}
+	if err := checkWithdrawalCredentials(data.WithdrawalCredentials); err != nil {
+		errs = append(errs, err)
+	}
+	if data.Amount.IsUint64() {
+		computed, err := computeDepositDataRoot(data.PubKey, data.WithdrawalCredentials, data.Amount.Uint64(), data.Signature)
+		if err != nil {
+			errs = append(errs, err)
+		} else if !bytes.Equal(computed[:], data.DepositDataRoot) {
+			errs = append(errs, fmt.Errorf("deposit_data_root is %x, the deposit data hashes to %x", []byte(data.DepositDataRoot), computed))
+		}
+	}
+	return errs
+}
+
+// checkWithdrawalCredentials checks the prefix of the credentials and that
+// an execution address is padded with 11 zero bytes.
+func checkWithdrawalCredentials(credentials []byte) error {
+	switch credentials[0] {
+	case blsWithdrawalPrefix:
+		return nil
+	case eth1AddressWithdrawalPrefix, compoundingWithdrawalPrefix:
+		if !bytes.Equal(credentials[1:12], make([]byte, 11)) {
+			return errors.New("withdrawal credentials of an execution address must have 11 zero bytes after the prefix")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown withdrawal credentials prefix 0x%02x", credentials[0])
+	}
+}
+
+// withdrawalAddress returns the execution address embedded in the last 20
+// bytes of 0x01 and 0x02 withdrawal credentials.
+func withdrawalAddress(credentials []byte) (common.Address, bool) {
+	if len(credentials) != withdrawalCredentialsLength {
+		return common.Address{}, false
+	}
+	if credentials[0] != eth1AddressWithdrawalPrefix && credentials[0] != compoundingWithdrawalPrefix {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(credentials[12:]), true
+}
+
+// withdrawalAddressMismatches returns an EntryError for every entry whose
+// withdrawal credentials carry another address than expected. Entries with
+// BLS credentials have no address to compare and are only warned about.
+func withdrawalAddressMismatches(deposits []DepositData, expected common.Address) []EntryError {
+	var problems []EntryError
+	for _, data := range deposits {
+		address, ok := withdrawalAddress(data.WithdrawalCredentials)
+		if !ok {
+			log.Printf("Warning: entry %d (%s) has no execution withdrawal address to compare with --expected-withdrawal-address", data.index, shortPubkey(data.PubKey.String()))
+			continue
+		}
+		if address != expected {
+			problems = append(problems, EntryError{
+				Index:  data.index,
+				PubKey: data.PubKey.String(),
+				Err:    fmt.Errorf("withdraws to %s, --expected-withdrawal-address is %s", address.Hex(), expected.Hex()),
+			})
+		}
+	}
+	return problems
+}
+
+// messageRootCheck counts the entries whose deposit_message_root and
+// signature were verified by verifyMessageRoots, those without one and the
+// top-ups whose amount was changed in review.
+type messageRootCheck struct {
+	roots, signatures, missing, changed int
+}
+
+// verifyMessageRoots checks the deposit_message_root of every entry that has
+// one against the root of its pubkey, withdrawal credentials and amount, and
+// the signature against the signing root of it on the entry's fork version.
+// Entries without the field are skipped, as are top-ups whose amount was
+// changed in review, which the signature no longer covers. A deposit with a
+// bad signature is accepted by the deposit contract but ignored by the beacon
+// chain.
+func verifyMessageRoots(deposits []DepositData, n network, knownNetwork bool) (messageRootCheck, []EntryError) {
+	var check messageRootCheck
+	var problems []EntryError
+	for _, data := range deposits {
+		if data.amountChanged {
+			check.changed++
+			continue
+		}
+		if data.DepositMessageRoot == "" {
+			check.missing++
+			continue
+		}
+		fail := func(err error) {
+			problems = append(problems, EntryError{Index: data.index, PubKey: data.PubKey.String(), Err: err})
+		}
+		root, err := hexField("deposit_message_root", data.DepositMessageRoot, 32)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		if err := data.checkFieldLengths(); err != nil {
+			fail(err)
+			continue
+		}
+		if !data.Amount.IsUint64() {
+			fail(fmt.Errorf("amount %s does not fit in uint64", data.Amount.String()))
+			continue
+		}
+		computed, err := depositMessageRoot(data.PubKey, data.WithdrawalCredentials, data.Amount.Uint64())
+		if err != nil {
+			fail(err)
+			continue
+		}
+		if !bytes.Equal(computed[:], root) {
+			fail(fmt.Errorf("deposit_message_root is %x, the deposit message hashes to %x", []byte(root), computed))
+			continue
+		}
+		check.roots++
+
+		forkVersion, err := depositForkVersion(data, n, knownNetwork)
+		if err != nil {
+			log.Printf("Warning: entry %d (%s): signature not verified: %v", data.index, shortPubkey(data.PubKey.String()), err)
+			continue
+		}
+		signingRoot := computeSigningRoot(computed, computeDepositDomain(forkVersion))
+		if err := verifyBLSSignature(data.PubKey, data.Signature, signingRoot); err != nil {
+			fail(fmt.Errorf("%w on fork version %x", err, forkVersion))
+			continue
+		}
+		check.signatures++
+	}
+	return check, problems
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"testing"
+)
+
+// TestVerifyMessageRoots checks an entry in the format of staking-deposit-cli
+// whose roots and BLS signature were computed independently of this package:
+// they must verify, and a signature with one byte changed must not.
+func TestVerifyMessageRoots(t *testing.T) {
+	file, err := os.ReadFile("testdata/deposit_data-holesky.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deposits, err := decodeDeposits(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := ValidateBatch(deposits); len(problems) != 0 {
+		t.Fatalf("problems %v", problems)
+	}
+	holesky, _ := networkByChainID(big.NewInt(holeskyChainID))
+	check, problems := verifyMessageRoots(deposits, holesky, true)
+	if len(problems) != 0 || check != (messageRootCheck{roots: 1, signatures: 1}) {
+		t.Fatalf("verified %+v with problems %v, want the root and signature of the entry", check, problems)
+	}
+
+	tampered := deposits[0]
+	tampered.Signature = append(HexBytes(nil), tampered.Signature...)
+	tampered.Signature[signatureLength-1] ^= 0x01
+	check, problems = verifyMessageRoots([]DepositData{tampered}, holesky, true)
+	if len(problems) != 1 || check.signatures != 0 {
+		t.Errorf("verified %+v with problems %v, want the tampered signature rejected", check, problems)
+	}
+}
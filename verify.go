@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// depositEvent is the decoded DepositEvent log of the deposit contract.
+type depositEvent struct {
+	PubKey                []byte
+	WithdrawalCredentials []byte
+	Amount                uint64
+	Signature             []byte
+	Index                 uint64
+}
+
+// findDepositEvent decodes the first DepositEvent emitted by address in the receipt.
+func findDepositEvent(contractABI abi.ABI, address common.Address, receipt *types.Receipt) (*depositEvent, error) {
+	event, ok := contractABI.Events["DepositEvent"]
+	if !ok {
+		return nil, errors.New("ABI has no DepositEvent")
+	}
+
+	for _, l := range receipt.Logs {
+		if l.Address != address || len(l.Topics) == 0 || l.Topics[0] != event.ID {
+			continue
+		}
+		return decodeDepositEvent(event, l)
+	}
+	return nil, errors.New("no DepositEvent in the receipt")
+}
+
+// decodeDepositEvent decodes a DepositEvent log.
+func decodeDepositEvent(event abi.Event, l *types.Log) (*depositEvent, error) {
+	values, err := event.Inputs.Unpack(l.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode DepositEvent: %w", err)
+	}
+	if len(values) != 5 {
+		return nil, fmt.Errorf("DepositEvent has %d fields, expected 5", len(values))
+	}
+	fields := make([][]byte, len(values))
+	for i, v := range values {
+		b, ok := v.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("unexpected DepositEvent field type %T", v)
+		}
+		fields[i] = b
+	}
+	if len(fields[2]) != 8 || len(fields[4]) != 8 {
+		return nil, errors.New("DepositEvent amount and index must be 8 bytes")
+	}
+	return &depositEvent{
+		PubKey:                fields[0],
+		WithdrawalCredentials: fields[1],
+		Amount:                binary.LittleEndian.Uint64(fields[2]),
+		Signature:             fields[3],
+		Index:                 binary.LittleEndian.Uint64(fields[4]),
+	}, nil
+}
+
+// verifyDeposit checks the post-conditions of a successfully mined deposit:
+// the contract emitted a DepositEvent matching the deposit data, and its
+// deposit count grew past the event index in that block.
+func verifyDeposit(ctx context.Context, client *ethclient.Client, contractABI abi.ABI, address common.Address, data DepositData, receipt *types.Receipt) (*depositEvent, error) {
+	event, err := findDepositEvent(contractABI, address, receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range []struct {
+		name string
+		want []byte
+		got  []byte
+	}{
+		{"pubkey", data.PubKey, event.PubKey},
+		{"withdrawal_credentials", data.WithdrawalCredentials, event.WithdrawalCredentials},
+		{"signature", data.Signature, event.Signature},
+	} {
+		if !bytes.Equal(field.want, field.got) {
+			return nil, fmt.Errorf("DepositEvent %s is 0x%x, expected 0x%x", field.name, field.got, field.want)
+		}
+	}
+	if !data.Amount.IsUint64() || event.Amount != data.Amount.Uint64() {
+		return nil, fmt.Errorf("DepositEvent amount is %d gwei, expected %s gwei", event.Amount, data.Amount.String())
+	}
+
+	before, err := readDepositCount(ctx, client, address, new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	after, err := readDepositCount(ctx, client, address, receipt.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if event.Index < before || event.Index >= after {
+		return nil, fmt.Errorf("deposit count went from %d to %d in block %d, which does not include index %d",
+			before, after, receipt.BlockNumber, event.Index)
+	}
+	return event, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// webhookEvent is the JSON payload posted to --webhook-url.
+type webhookEvent struct {
+	Event       string  `json:"event"`
+	Time        string  `json:"time"`
+	Index       int     `json:"index"`
+	PubKey      string  `json:"pubkey"`
+	Amount      string  `json:"amount_gwei"`
+	TxHash      string  `json:"tx_hash,omitempty"`
+	BlockNumber uint64  `json:"block_number,omitempty"`
+	Status      *uint64 `json:"status,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhook(url string) *webhook {
+	return &webhook{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (w *webhook) post(payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (w *webhook) BeforeSubmit(data DepositData) error {
+	return w.post(webhookEvent{
+		Event:  "before_submit",
+		Time:   time.Now().UTC().Format(time.RFC3339),
+		Index:  data.index,
+		PubKey: data.PubKey.String(),
+		Amount: data.Amount.String(),
+	})
+}
+
+func (w *webhook) AfterSubmit(data DepositData, receipt *types.Receipt, err error) error {
+	event := webhookEvent{
+		Event:  "after_submit",
+		Time:   time.Now().UTC().Format(time.RFC3339),
+		Index:  data.index,
+		PubKey: data.PubKey.String(),
+		Amount: data.Amount.String(),
+	}
+	if receipt != nil {
+		event.TxHash = receipt.TxHash.Hex()
+		event.BlockNumber = receipt.BlockNumber.Uint64()
+		event.Status = &receipt.Status
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return w.post(event)
+}